@@ -13,6 +13,16 @@ const (
 	attributeUnit         = "$unit"
 	attributeSettable     = "$settable"
 	attributeRetained     = "$retained"
+	attributeDescription  = "$description"
+	attributeTarget       = "$target"
+
+	// Homie 3 attributes
+	attributeLocalIP        = "$localip"
+	attributeMAC            = "$mac"
+	attributeFirmware       = "$fw"
+	attributeImplementation = "$implementation"
+	attributeArray          = "$array"
+	attributeStats          = "$stats"
 )
 
 // TopicValuePair represents a MQTT topic and value pair
@@ -0,0 +1,177 @@
+package homie
+
+import (
+	"fmt"
+	"sort"
+)
+
+// BatchSetter is the signature of the callback receiving all the values published by a batch at once
+type BatchSetter func(values []TopicValuePair)
+
+// Batch collects the new values of several properties, to publish them as one group
+type Batch struct {
+	device  *Device
+	updates []batchUpdate
+	errs    []error
+}
+
+type batchUpdate struct {
+	prop  *Property
+	value interface{}
+}
+
+type batchMessage struct {
+	prop    *Property
+	topic   string
+	payload string
+}
+
+// Set adds a new value of a property to the batch
+func (b *Batch) Set(prop *Property, value interface{}) *Batch {
+	if prop == nil {
+		b.errs = append(b.errs, fmt.Errorf("%w: nil property", ErrUnknownProperty))
+		return b
+	}
+	if err := prop.checkArray(); err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	b.updates = append(b.updates, batchUpdate{prop, value})
+	return b
+}
+
+// OnBatch installs a callback receiving all the values published by a batch at once.
+// When installed, it replaces the Setter callbacks for the values published by a batch.
+func (d *Device) OnBatch(setter BatchSetter) *Device {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.batchSetter = setter
+	return d
+}
+
+// Batch sets the values of several properties as one group.
+//
+// All the values are validated first: if any value is invalid, no value is changed and a *BatchError lists all the problems.
+// Otherwise the values are stored, then published together: the callback installed with OnBatch receives all of them at once
+// (or each value is sent to its Setter callback when there's no batch callback), then they are sent through the transport.
+//
+// The publish policies and dead bands are applied, but the values are never delayed by a rate limit.
+//
+//	err := device.Batch(func(b *homie.Batch) {
+//		b.Set(node.Property("temperature"), 21.5)
+//		b.Set(node.Property("humidity"), 40)
+//	})
+func (d *Device) Batch(fill func(b *Batch)) error {
+	batch := &Batch{device: d}
+	fill(batch)
+	return batch.commit()
+}
+
+// Update sets the values of several properties of the node, by property ID, as one group (see Device.Batch)
+func (n *Node) Update(values map[string]interface{}) error {
+	ids := make([]string, 0, len(values))
+	for id := range values {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	batch := &Batch{device: n.device}
+	for _, id := range ids {
+		prop := n.Property(id)
+		if prop == nil {
+			batch.errs = append(batch.errs, fmt.Errorf("%w: '%s'", ErrUnknownProperty, id))
+			continue
+		}
+		batch.Set(prop, values[id])
+	}
+	return batch.commit()
+}
+
+// commit validates, stores and publishes the values of the batch
+func (b *Batch) commit() error {
+	errs := b.errs
+	payloads := make([]string, len(b.updates))
+	for i, update := range b.updates {
+		update.prop.mutex.RLock()
+		payload, err := update.prop.formatValue(update.value)
+		topic := update.prop.prefix
+		update.prop.mutex.RUnlock()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", topic, err))
+			continue
+		}
+		payloads[i] = payload
+	}
+	if len(errs) > 0 {
+		return &BatchError{Errors: errs}
+	}
+
+	messages := make([]batchMessage, 0, len(b.updates))
+	for i, update := range b.updates {
+		if message, ok := update.prop.store(payloads[i]); ok {
+			messages = append(messages, message)
+		}
+	}
+	return b.publish(messages)
+}
+
+// store the payload of a batch, and returns the message to publish if the publish policy allows it
+func (p *Property) store(payload string) (batchMessage, bool) {
+	options := p.publishOptions()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.value = payload
+	// a value waiting for the end of a rate limit interval is now obsolete
+	p.hasPending = false
+	current := options.clock.Now()
+	if !p.shouldPublish(payload, options.policy, current) {
+		return batchMessage{}, false
+	}
+	p.publishedValue = payload
+	p.publishedAt = current
+	return batchMessage{p, p.prefix, payload}, true
+}
+
+// publish the messages to the batch callback (or each Setter callback), then through the transport
+func (b *Batch) publish(messages []batchMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	var batchSetter BatchSetter
+	var transport Transport
+	if b.device != nil {
+		b.device.mutex.RLock()
+		batchSetter, transport = b.device.batchSetter, b.device.transport
+		b.device.mutex.RUnlock()
+	}
+	if batchSetter == nil {
+		var firstErr error
+		for _, message := range messages {
+			err := message.prop.send(message.topic, message.payload)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+
+	values := make([]TopicValuePair, len(messages))
+	for i, message := range messages {
+		values[i] = TopicValuePair{message.topic, message.payload}
+	}
+	batchSetter(values)
+	if transport == nil {
+		return nil
+	}
+	var firstErr error
+	for _, message := range messages {
+		err := transport.Publish(message.topic, QoS, message.prop.definition().retained, message.payload)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
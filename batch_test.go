@@ -0,0 +1,130 @@
+package homie
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchWithBatchSetter(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName")
+	node := device.AddNode("bme280", "BME280", "bme280")
+	node.AddProperty("temperature", "Temperature", TypeFloat).SetPrecision(1)
+	node.AddProperty("humidity", "Humidity", TypeInteger)
+	batches := make([][]TopicValuePair, 0)
+	device.OnBatch(func(values []TopicValuePair) {
+		batches = append(batches, values)
+	})
+	device.OnSet(func(topic, value string, dataType PropertyType) {
+		t.Errorf("unexpected call to the setter: %s=%s", topic, value)
+	})
+
+	err := device.Batch(func(b *Batch) {
+		b.Set(node.Property("temperature"), 21.54)
+		b.Set(node.Property("humidity"), 40)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][]TopicValuePair{{
+		{"homie/deviceID/bme280/temperature", "21.5"},
+		{"homie/deviceID/bme280/humidity", "40"},
+	}}, batches)
+	assert.Equal(t, "21.5", node.Property("temperature").Value())
+}
+
+func TestBatchFallsBackToSetter(t *testing.T) {
+	values := make([]string, 0)
+	device := NewDevice("deviceID", "deviceName").OnSet(func(topic, value string, dataType PropertyType) {
+		values = append(values, topic+"="+value)
+	})
+	device.AddNode("bme280", "BME280", "bme280").
+		AddProperty("temperature", "Temperature", TypeFloat).Node().
+		AddProperty("humidity", "Humidity", TypeInteger).Node().
+		AddProperty("pressure", "Pressure", TypeFloat)
+
+	err := device.Node("bme280").Update(map[string]interface{}{
+		"temperature": 21.5,
+		"humidity":    40,
+		"pressure":    1013.25,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"homie/deviceID/bme280/humidity=40",
+		"homie/deviceID/bme280/pressure=1013.25",
+		"homie/deviceID/bme280/temperature=21.5",
+	}, values)
+}
+
+func TestBatchInvalidValueChangesNothing(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName")
+	node := device.AddNode("bme280", "BME280", "bme280")
+	node.AddProperty("temperature", "Temperature", TypeFloat).SetPrecision(1)
+	node.AddProperty("humidity", "Humidity", TypeInteger).SetRange(0, 100)
+	node.Update(map[string]interface{}{"temperature": 20, "humidity": 50})
+	calls := 0
+	device.OnBatch(func(values []TopicValuePair) {
+		calls++
+	})
+
+	err := node.Update(map[string]interface{}{
+		"temperature": 21.5,
+		"humidity":    140,
+		"unknown":     1,
+	})
+	require.Error(t, err)
+	var batchErr *BatchError
+	require.True(t, errors.As(err, &batchErr))
+	assert.Len(t, batchErr.Errors, 2)
+	assert.True(t, errors.Is(err, ErrInvalidValue))
+	assert.True(t, errors.Is(err, ErrUnknownProperty))
+
+	assert.Equal(t, 0, calls)
+	assert.Equal(t, "20.0", node.Property("temperature").Value())
+	assert.Equal(t, "50", node.Property("humidity").Value())
+
+	err = device.Batch(func(b *Batch) {
+		b.Set(node.Property("missing"), 1)
+	})
+	assert.True(t, errors.Is(err, ErrUnknownProperty))
+}
+
+func TestBatchWithTransport(t *testing.T) {
+	transport := newMockTransport()
+	device := NewDevice("deviceID", "deviceName").SetTransport(transport).SetPublishPolicy(PublishOnChange)
+	node := device.AddNode("bme280", "BME280", "bme280")
+	node.AddProperty("temperature", "Temperature", TypeFloat)
+	node.AddProperty("pressure", "Pressure", TypeFloat).SetRetained(false)
+	require.NoError(t, device.Publish())
+	require.NoError(t, node.Update(map[string]interface{}{"temperature": 20, "pressure": 1000}))
+	transport.messages = transport.messages[:0]
+
+	batches := make([][]TopicValuePair, 0)
+	device.OnBatch(func(values []TopicValuePair) {
+		batches = append(batches, values)
+	})
+	// only the values which changed are published
+	require.NoError(t, node.Update(map[string]interface{}{"temperature": 20, "pressure": 1001}))
+	assert.Equal(t, [][]TopicValuePair{{{"homie/deviceID/bme280/pressure", "1001"}}}, batches)
+	assert.Equal(t, []mockMessage{{"homie/deviceID/bme280/pressure", QoS, false, "1001"}}, transport.messages)
+}
+
+func TestBatchIsNotRateLimited(t *testing.T) {
+	clock := newFakeClock()
+	values := make([]string, 0)
+	node := NewDevice("deviceID", "deviceName").
+		SetClock(clock).
+		SetRateLimit(time.Second).
+		OnSet(collectValues(&values)).
+		AddNode("bme280", "BME280", "bme280")
+	node.AddProperty("humidity", "Humidity", TypeInteger)
+
+	node.Property("humidity").Set(10).Set(20)
+	require.NoError(t, node.Update(map[string]interface{}{"humidity": 30}))
+	assert.Equal(t, []string{"10", "30"}, values)
+
+	// the value waiting for the end of the interval was replaced by the batch
+	clock.Advance(time.Second)
+	assert.Equal(t, []string{"10", "30"}, values)
+}
@@ -0,0 +1,95 @@
+package homie
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const broadcastTopic = "$broadcast"
+
+// BroadcastHandler is the signature of the callback receiving a broadcast message
+type BroadcastHandler func(level, message string)
+
+// BroadcastTopic returns the topic of a broadcast message for the level: <root>/$broadcast/<level>
+//
+// see documentation: https://homieiot.github.io/specification/#broadcast-channel
+func BroadcastTopic(root, level string) string {
+	return path.Join(root, broadcastTopic, level)
+}
+
+// OnBroadcast installs a callback receiving the broadcast messages sent to all devices for that level (like "alert").
+// An empty level receives the broadcast messages of all levels.
+//
+// The subscription is done by Publish when a transport is attached, or you can send the messages to HandleMessage.
+func (d *Device) OnBroadcast(level string, handler BroadcastHandler) *Device {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.broadcastHandlers == nil {
+		d.broadcastHandlers = make(map[string]BroadcastHandler, 1)
+	}
+	d.broadcastHandlers[level] = handler
+	return d
+}
+
+// getBroadcastPrefix returns the root of the broadcast topics for the Homie version of the device.
+// The mutex must be held by the caller.
+func (d *Device) getBroadcastPrefix() string {
+	if d.majorVersion() == "5" {
+		return BroadcastTopic(path.Join(d.root, "5"), "")
+	}
+	return BroadcastTopic(d.root, "")
+}
+
+// getBroadcastSubscriptions returns the topics to subscribe for the broadcast handlers.
+// The mutex must be held by the caller.
+func (d *Device) getBroadcastSubscriptions() []string {
+	topics := make([]string, 0, len(d.broadcastHandlers))
+	for level := range d.broadcastHandlers {
+		if level == "" {
+			topics = append(topics, path.Join(d.getBroadcastPrefix(), "#"))
+			continue
+		}
+		topics = append(topics, path.Join(d.getBroadcastPrefix(), level))
+	}
+	return topics
+}
+
+// handleBroadcast sends a broadcast message to its handler. It returns false if the topic is not a broadcast message.
+func (d *Device) handleBroadcast(topic, payload string) (bool, error) {
+	d.mutex.RLock()
+	prefix := d.getBroadcastPrefix() + "/"
+	level := strings.TrimPrefix(topic, prefix)
+	handler := d.broadcastHandlers[level]
+	if handler == nil {
+		handler = d.broadcastHandlers[""]
+	}
+	d.mutex.RUnlock()
+
+	if !strings.HasPrefix(topic, prefix) {
+		return false, nil
+	}
+	if handler == nil {
+		return true, &CommandError{Topic: topic, Payload: payload, Err: ErrUnknownTopic}
+	}
+	handler(level, payload)
+	return true, nil
+}
+
+// Broadcast sends a message to all the devices under the root topic of the discovery, through its transport.
+//
+// see documentation: https://homieiot.github.io/specification/#broadcast-channel
+func (d *Discovery) Broadcast(level, message string) error {
+	if !IsValidID(level) {
+		return fmt.Errorf("%w: '%s'", ErrInvalidID, level)
+	}
+	d.mutex.Lock()
+	transport := d.transport
+	root := d.root
+	d.mutex.Unlock()
+	if transport == nil {
+		return ErrNoTransport
+	}
+	return transport.Publish(BroadcastTopic(root, level), QoS, false, message)
+}
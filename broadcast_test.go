@@ -0,0 +1,62 @@
+package homie
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroadcastTopic(t *testing.T) {
+	assert.Equal(t, "homie/$broadcast/alert", BroadcastTopic(DefaultRoot, "alert"))
+	assert.Equal(t, "unit/test/$broadcast/shutdown", BroadcastTopic("unit/test", "shutdown"))
+}
+
+func TestDeviceBroadcastSubscription(t *testing.T) {
+	received := ""
+	transport := newMockTransport()
+	device := NewDevice("deviceID", "deviceName").
+		SetRoot("unit/test").
+		SetTransport(transport).
+		OnBroadcast("alert", func(level, message string) {
+			received = level + ": " + message
+		})
+	require.NoError(t, device.Publish())
+	require.NotNil(t, transport.subscriptions["unit/test/$broadcast/alert"])
+
+	transport.send("unit/test/$broadcast/alert", "fire!")
+	assert.Equal(t, "alert: fire!", received)
+}
+
+func TestDeviceBroadcastAllLevels(t *testing.T) {
+	levels := make([]string, 0)
+	device := NewDevice("deviceID", "deviceName").
+		SetVersion(Version5).
+		OnBroadcast("", func(level, message string) {
+			levels = append(levels, level)
+		})
+	assert.Equal(t, []string{"homie/5/$broadcast/#"}, device.getBroadcastSubscriptions())
+
+	assert.NoError(t, device.HandleMessage("homie/5/$broadcast/alert", "message"))
+	assert.NoError(t, device.HandleMessage("homie/5/$broadcast/shutdown", "message"))
+	assert.Equal(t, []string{"alert", "shutdown"}, levels)
+}
+
+func TestDeviceBroadcastUnknownLevel(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName").OnBroadcast("alert", func(level, message string) {})
+	err := device.HandleMessage("homie/$broadcast/shutdown", "now")
+	assert.True(t, errors.Is(err, ErrUnknownTopic))
+}
+
+func TestControllerBroadcast(t *testing.T) {
+	transport := newMockTransport()
+	discovery := NewDiscovery()
+	assert.True(t, errors.Is(discovery.Broadcast("alert", "message"), ErrNoTransport))
+
+	require.NoError(t, discovery.Subscribe(transport))
+	assert.NoError(t, discovery.Broadcast("shutdown", "now"))
+	assert.Equal(t, []mockMessage{{"homie/$broadcast/shutdown", QoS, false, "now"}}, transport.messages)
+
+	assert.True(t, errors.Is(discovery.Broadcast("$invalid", "message"), ErrInvalidID))
+}
@@ -0,0 +1,80 @@
+package homie
+
+import "fmt"
+
+// PropertyOption configures a property created by a Builder
+type PropertyOption func(p *Property)
+
+// Builder defines a device without panicking on invalid definitions:
+// all the problems are collected and reported at once by Build.
+//
+//	device, err := homie.NewBuilder("my-sensor", "My sensor").
+//		AddNode("bme280", "BME280", "bme280").
+//		AddProperty("temperature", "Temperature", homie.TypeFloat, func(p *homie.Property) { p.SetUnit("°C") }).
+//		Build()
+type Builder struct {
+	device      *Device
+	node        *Node
+	nodeIDs     map[string]bool
+	propertyIDs map[string]bool
+	errs        []error
+}
+
+// NewBuilder starts the definition of a device
+func NewBuilder(id, name string) *Builder {
+	builder := &Builder{
+		nodeIDs: make(map[string]bool),
+		errs:    validateDefinition("device", id, name, false),
+	}
+	if len(builder.errs) == 0 {
+		builder.device = NewDevice(id, name)
+	}
+	return builder
+}
+
+// AddNode adds a node to the device. The next properties are added to this node.
+func (b *Builder) AddNode(id, name, nodeType string) *Builder {
+	errs := validateDefinition("node", id, name, b.nodeIDs[id])
+	b.nodeIDs[id] = true
+	b.propertyIDs = make(map[string]bool)
+	b.node = nil
+	if len(errs) > 0 {
+		b.errs = append(b.errs, errs...)
+		return b
+	}
+	if b.device != nil {
+		b.node = b.device.AddNode(id, name, nodeType)
+	}
+	return b
+}
+
+// AddProperty adds a property to the last node, and applies the options to the property
+func (b *Builder) AddProperty(id, name string, dataType PropertyType, options ...PropertyOption) *Builder {
+	if b.propertyIDs == nil {
+		b.errs = append(b.errs, validateDefinition("property", id, name, false)...)
+		b.errs = append(b.errs, fmt.Errorf("property '%s' is not attached to a node", id))
+		return b
+	}
+	errs := validateDefinition("property", id, name, b.propertyIDs[id])
+	b.propertyIDs[id] = true
+	if len(errs) > 0 {
+		b.errs = append(b.errs, errs...)
+		return b
+	}
+	if b.node != nil {
+		prop := b.node.AddProperty(id, name, dataType)
+		for _, option := range options {
+			option(prop)
+		}
+	}
+	return b
+}
+
+// Build returns the device, or a *ValidationError listing all the problems found in the definition
+func (b *Builder) Build() (*Device, error) {
+	err := newValidationError(b.errs)
+	if err != nil {
+		return nil, err
+	}
+	return b.device, nil
+}
@@ -0,0 +1,75 @@
+package homie
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDeviceE(t *testing.T) {
+	device, err := NewDeviceE("deviceID", "deviceName")
+	assert.NoError(t, err)
+	assert.NotNil(t, device)
+
+	_, err = NewDeviceE("$device", "")
+	var validationError *ValidationError
+	require.True(t, errors.As(err, &validationError))
+	assert.Len(t, validationError.Errors, 2)
+	assert.True(t, errors.Is(err, ErrInvalidID))
+	assert.True(t, errors.Is(err, ErrMissingName))
+}
+
+func TestTryAddNodeAndProperty(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName")
+	node, err := device.TryAddNode("node1", "node1 name", "test1")
+	require.NoError(t, err)
+
+	_, err = device.TryAddNode("node1", "node1 name", "test1")
+	assert.True(t, errors.Is(err, ErrDuplicateID))
+	_, err = device.TryAddNode("node_2", "node2 name", "test2")
+	assert.True(t, errors.Is(err, ErrInvalidID))
+
+	prop, err := node.TryAddProperty("prop1", "prop1 name", TypeInteger)
+	require.NoError(t, err)
+	assert.Equal(t, prop, node.Property("prop1"))
+
+	_, err = node.TryAddProperty("prop1", "", TypeInteger)
+	assert.True(t, errors.Is(err, ErrDuplicateID))
+	assert.True(t, errors.Is(err, ErrMissingName))
+	assert.Len(t, node.properties, 1)
+}
+
+func TestBuilder(t *testing.T) {
+	device, err := NewBuilder("deviceID", "deviceName").
+		AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeFloat, func(p *Property) { p.SetUnit("°C") }).
+		AddProperty("prop2", "prop2 name", TypeBoolean).
+		Build()
+	require.NoError(t, err)
+	assert.Equal(t, "°C", device.Node("node1").Property("prop1").unit)
+	assert.NotNil(t, device.Node("node1").Property("prop2"))
+}
+
+func TestBuilderReportsAllErrors(t *testing.T) {
+	device, err := NewBuilder("device ID", "deviceName").
+		AddProperty("prop0", "prop0 name", TypeFloat).
+		AddNode("node1", "", "test1").
+		AddProperty("prop1", "prop1 name", TypeFloat).
+		AddProperty("prop1", "prop1 name", TypeFloat).
+		AddNode("node1", "node1 name", "test1").
+		AddProperty("$prop2", "prop2 name", TypeFloat).
+		Build()
+	assert.Nil(t, device)
+
+	var validationError *ValidationError
+	require.True(t, errors.As(err, &validationError))
+	assert.Len(t, validationError.Errors, 6)
+	assert.Contains(t, err.Error(), "device ID 'device ID'")
+	assert.Contains(t, err.Error(), "property 'prop0' is not attached to a node")
+	assert.Contains(t, err.Error(), "node 'node1' has no name")
+	assert.Contains(t, err.Error(), "property ID 'prop1' is already used")
+	assert.Contains(t, err.Error(), "node ID 'node1' is already used")
+	assert.Contains(t, err.Error(), "property ID '$prop2' cannot start with '$'")
+}
@@ -0,0 +1,36 @@
+package homie
+
+import "time"
+
+// Clock gives the current time and schedules the delayed publications of the properties.
+// The default clock uses the time package: another clock can be installed with Device.SetClock, typically in tests.
+type Clock interface {
+	Now() time.Time
+	// AfterFunc calls f in its own goroutine after the duration has elapsed
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a function scheduled by Clock.AfterFunc
+type Timer interface {
+	// Stop prevents the function from being called. It returns false if the function was already called or stopped.
+	Stop() bool
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// SetClock replaces the clock used by the publish policies and the rate limiters of the properties
+func (d *Device) SetClock(clock Clock) *Device {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.clock = clock
+	return d
+}
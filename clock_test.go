@@ -0,0 +1,107 @@
+package homie
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock only moves forward with Advance, which calls the functions scheduled in the meantime
+type fakeClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock   *fakeClock
+	when    time.Time
+	f       func()
+	stopped bool
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	timer := &fakeTimer{clock: c, when: c.now.Add(d), f: f}
+	c.timers = append(c.timers, timer)
+	return timer
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+
+	stopped := t.stopped
+	t.stopped = true
+	return !stopped
+}
+
+// Advance moves the time forward, calling the functions scheduled on the way (in the calling goroutine)
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	end := c.now.Add(d)
+	for {
+		sort.SliceStable(c.timers, func(i, j int) bool {
+			return c.timers[i].when.Before(c.timers[j].when)
+		})
+		if len(c.timers) == 0 || c.timers[0].when.After(end) {
+			break
+		}
+		timer := c.timers[0]
+		c.timers = c.timers[1:]
+		if timer.stopped {
+			continue
+		}
+		timer.stopped = true
+		c.now = timer.when
+		c.mutex.Unlock()
+		timer.f()
+		c.mutex.Lock()
+	}
+	c.now = end
+	c.mutex.Unlock()
+}
+
+func TestFakeClock(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+	calls := make([]time.Duration, 0)
+	clock.AfterFunc(2*time.Second, func() { calls = append(calls, clock.Now().Sub(start)) })
+	clock.AfterFunc(time.Second, func() { calls = append(calls, clock.Now().Sub(start)) })
+	stopped := clock.AfterFunc(time.Second, func() { calls = append(calls, 0) })
+	assert.True(t, stopped.Stop())
+	assert.False(t, stopped.Stop())
+
+	clock.Advance(3 * time.Second)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, calls)
+	assert.Equal(t, 3*time.Second, clock.Now().Sub(start))
+}
+
+func TestSystemClock(t *testing.T) {
+	clock := systemClock{}
+	assert.WithinDuration(t, time.Now(), clock.Now(), time.Second)
+
+	done := make(chan struct{})
+	clock.AfterFunc(time.Millisecond, func() { close(done) })
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("function not called")
+	}
+}
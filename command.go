@@ -0,0 +1,71 @@
+package homie
+
+import (
+	"strings"
+)
+
+// CommandHandler is the signature of the callback receiving a set command for a property.
+// The raw value has already been validated against the property data type and format.
+// Returning an error rejects the command.
+type CommandHandler func(p *Property, raw string) error
+
+// HandleMessage processes an incoming set command sent to a property setter topic:
+// homie/<deviceID>/<nodeID>/<propertyID>/set
+//
+// The payload is validated against the property $datatype and $format, then passed to the
+// handler installed with Property.OnCommand. If the command is accepted, the new value is
+// sent back to the property topic (unless disabled with Property.EchoCommand).
+//
+// It returns a *CommandError if the topic is unknown, the property is not settable,
+// or the payload is rejected.
+//
+// Broadcast messages are also dispatched to the handlers installed with OnBroadcast.
+//
+// see documentation: https://homieiot.github.io/specification/#property-command-topic
+func (d *Device) HandleMessage(topic, payload string) error {
+	if handled, err := d.handleBroadcast(topic, payload); handled {
+		return err
+	}
+	prop := d.findSetterProperty(topic)
+	if prop == nil {
+		return &CommandError{Topic: topic, Payload: payload, Err: ErrUnknownTopic}
+	}
+	definition := prop.definition()
+	if !definition.settable {
+		return &CommandError{Topic: topic, Payload: payload, Err: ErrNotSettable}
+	}
+	err := validateValue(definition.dataType, definition.format, payload)
+	if err != nil {
+		return &CommandError{Topic: topic, Payload: payload, Err: err}
+	}
+	if definition.commandHandler != nil {
+		err = definition.commandHandler(prop, payload)
+		if err != nil {
+			return &CommandError{Topic: topic, Payload: payload, Err: err}
+		}
+	}
+	if definition.echo {
+		// the echo confirms the command: it is always published
+		_ = prop.set(payload, true)
+	}
+	return nil
+}
+
+// onMessage is the handler installed on the transport subscriptions:
+// invalid commands are simply ignored
+func (d *Device) onMessage(topic, payload string) {
+	_ = d.HandleMessage(topic, payload)
+}
+
+// findSetterProperty returns the property from a setter topic, settable or not.
+// It returns nil if no property matches the topic.
+func (d *Device) findSetterProperty(topic string) *Property {
+	d.mutex.RLock()
+	prefix := d.prefix + "/"
+	d.mutex.RUnlock()
+
+	if !strings.HasPrefix(topic, prefix) || !strings.HasSuffix(topic, "/set") {
+		return nil
+	}
+	return d.findProperty(strings.TrimSuffix(strings.TrimPrefix(topic, prefix), "/set"))
+}
@@ -0,0 +1,92 @@
+package homie
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleMessageErrors(t *testing.T) {
+	testData := []struct {
+		topic   string
+		payload string
+		err     error
+	}{
+		{"homie/otherID/node1/switch/set", "true", ErrUnknownTopic},
+		{"homie/deviceID/node1/switch", "true", ErrUnknownTopic},
+		{"homie/deviceID/node2/switch/set", "true", ErrUnknownTopic},
+		{"homie/deviceID/node1/unknown/set", "true", ErrUnknownTopic},
+		{"homie/deviceID/node1/status/set", "value", ErrNotSettable},
+		{"homie/deviceID/node1/switch/set", "yes", ErrInvalidValue},
+		{"homie/deviceID/node1/level/set", "101", ErrInvalidValue},
+		{"homie/deviceID/node1/level/set", "5.5", ErrInvalidValue},
+	}
+	device := NewDevice("deviceID", "deviceName")
+	device.AddNode("node1", "node1 name", "test1").
+		AddProperty("switch", "switch", TypeBoolean).Settable(true).Node().
+		AddProperty("level", "level", TypeInteger).SetFormat("0:100").Settable(true).Node().
+		AddProperty("status", "status", TypeString)
+	for _, testItem := range testData {
+		t.Run(testItem.topic+" "+testItem.payload, func(t *testing.T) {
+			err := device.HandleMessage(testItem.topic, testItem.payload)
+			var commandError *CommandError
+			assert.True(t, errors.As(err, &commandError))
+			assert.Equal(t, testItem.topic, commandError.Topic)
+			assert.True(t, errors.Is(err, testItem.err))
+		})
+	}
+}
+
+func TestHandleMessageEcho(t *testing.T) {
+	values := make([]TopicValuePair, 0)
+	device := NewDevice("deviceID", "deviceName").OnSet(func(topic, value string, dataType PropertyType) {
+		values = append(values, TopicValuePair{topic, value})
+	})
+	device.AddNode("node1", "node1 name", "test1").
+		AddProperty("level", "level", TypeInteger).SetFormat("0:100").Settable(true)
+	err := device.HandleMessage("homie/deviceID/node1/level/set", "50")
+	assert.NoError(t, err)
+	assert.Equal(t, "50", device.Node("node1").Property("level").GetValue().Value)
+	assert.Equal(t, []TopicValuePair{{"homie/deviceID/node1/level", "50"}}, values)
+}
+
+func TestHandleMessageWithoutEcho(t *testing.T) {
+	values := make([]TopicValuePair, 0)
+	device := NewDevice("deviceID", "deviceName").OnSet(func(topic, value string, dataType PropertyType) {
+		values = append(values, TopicValuePair{topic, value})
+	})
+	device.AddNode("node1", "node1 name", "test1").
+		AddProperty("level", "level", TypeInteger).Settable(true).EchoCommand(false)
+	err := device.HandleMessage("homie/deviceID/node1/level/set", "50")
+	assert.NoError(t, err)
+	assert.Empty(t, values)
+}
+
+func TestHandleMessageCommandHandler(t *testing.T) {
+	received := ""
+	device := NewDevice("deviceID", "deviceName")
+	device.AddNode("node1", "node1 name", "test1").
+		AddProperty("switch", "switch", TypeBoolean).Settable(true).
+		OnCommand(func(p *Property, raw string) error {
+			received = raw
+			return nil
+		})
+	err := device.HandleMessage("homie/deviceID/node1/switch/set", "true")
+	assert.NoError(t, err)
+	assert.Equal(t, "true", received)
+	assert.Equal(t, "true", device.Node("node1").Property("switch").GetValue().Value)
+}
+
+func TestHandleMessageRejectedByHandler(t *testing.T) {
+	rejected := errors.New("rejected")
+	device := NewDevice("deviceID", "deviceName")
+	device.AddNode("node1", "node1 name", "test1").
+		AddProperty("switch", "switch", TypeBoolean).Settable(true).
+		OnCommand(func(p *Property, raw string) error {
+			return rejected
+		})
+	err := device.HandleMessage("homie/deviceID/node1/switch/set", "true")
+	assert.True(t, errors.Is(err, rejected))
+	assert.Equal(t, "", device.Node("node1").Property("switch").GetValue().Value)
+}
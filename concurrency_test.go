@@ -0,0 +1,155 @@
+package homie
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// these tests are meant to be run with the race detector: go test -race
+
+const concurrencyLoops = 100
+
+func TestConcurrentSetAndRead(t *testing.T) {
+	transport := newMockTransport()
+	device := NewDevice("deviceID", "deviceName").SetTransport(transport)
+	node := device.AddNode("node1", "node1 name", "test1")
+	node.AddProperty("prop1", "prop1 name", TypeInteger).Settable(true)
+	node.AddProperty("prop2", "prop2 name", TypeFloat)
+	array := device.AddNodeArray("relays", "Relays", "relay", 1, 4)
+	array.AddProperty("power", "Power", TypeBoolean).Settable(true)
+	require.NoError(t, device.Publish())
+
+	wg := sync.WaitGroup{}
+	run := func(action func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < concurrencyLoops; i++ {
+				action(i)
+			}
+		}()
+	}
+	run(func(i int) { node.Property("prop1").Set(i) })
+	run(func(i int) { node.Property("prop1").Set(-i) })
+	run(func(i int) { node.Property("prop2").Set(float64(i) / 10) })
+	run(func(i int) { array.Index(i%3 + 1).Property("power").Set(i%2 == 0) })
+	run(func(i int) { transport.send("homie/deviceID/node1/prop1/set", strconv.Itoa(i)) })
+	run(func(i int) { transport.send("homie/deviceID/relays-4/power/set", "true") })
+	run(func(i int) { _ = device.GetValues() })
+	run(func(i int) { _ = device.GetHomieAttributes() })
+	run(func(i int) { _ = device.GetDescription() })
+	run(func(i int) { _ = device.GetPropertySetters() })
+	run(func(i int) { node.Property("prop2").SetUnit("°C" + strconv.Itoa(i)) })
+	wg.Wait()
+
+	assert.Equal(t, "true", array.Index(4).Property("power").GetValue().Value)
+}
+
+func TestConcurrentStructureChanges(t *testing.T) {
+	transport := newMockTransport()
+	device := NewDevice("deviceID", "deviceName").SetTransport(transport)
+	node := device.AddNode("node1", "node1 name", "test1")
+	require.NoError(t, device.Publish())
+
+	wg := sync.WaitGroup{}
+	for g := 0; g < 4; g++ {
+		g := g
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < concurrencyLoops; i++ {
+				id := "prop-" + strconv.Itoa(g) + "-" + strconv.Itoa(i)
+				node.AddProperty(id, id, TypeInteger).Set(i)
+				device.AddNode("node-"+strconv.Itoa(g)+"-"+strconv.Itoa(i), "node", "test")
+			}
+		}()
+	}
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < concurrencyLoops; i++ {
+			_ = device.GetHomieAttributes()
+			_ = device.GetValues()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < concurrencyLoops; i++ {
+			_ = device.Publish()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < concurrencyLoops; i++ {
+			device.SetVersion([]string{Version3, Version4, Version5}[i%3])
+		}
+	}()
+	wg.Wait()
+
+	device.SetVersion(Version4)
+	require.NoError(t, device.Publish())
+	assert.Len(t, device.GetValues(), 4*concurrencyLoops)
+	assert.Equal(t, StateReady, DeviceState(device.GetState().Value))
+}
+
+func TestCallbacksCanCallBackIntoDevice(t *testing.T) {
+	transport := newMockTransport()
+	device := NewDevice("deviceID", "deviceName").SetTransport(transport)
+	prop := device.AddNode("node1", "node1 name", "test1").AddProperty("prop1", "prop1 name", TypeInteger).Settable(true)
+
+	values := 0
+	device.OnSet(func(topic, value string, dataType PropertyType) {
+		values = len(device.GetValues())
+	})
+	device.OnStateChange(func(from, to DeviceState) {
+		_ = device.GetHomieAttributes()
+	})
+	prop.OnCommand(func(p *Property, raw string) error {
+		p.SetUnit("W")
+		return nil
+	})
+	transport.onPublish = func(topic, payload string) {
+		_ = device.GetState()
+	}
+
+	require.NoError(t, device.Publish())
+	transport.send("homie/deviceID/node1/prop1/set", "10")
+	assert.Equal(t, 1, values)
+	assert.Equal(t, "10", prop.GetValue().Value)
+}
+
+func TestConcurrentDiscovery(t *testing.T) {
+	source := NewDevice("deviceID", "deviceName")
+	source.AddNode("node1", "node1 name", "test1").AddProperty("prop1", "prop1 name", TypeInteger)
+	attributes := source.GetHomieAttributes()
+	transport := newMockTransport()
+	discovery := NewDiscovery()
+	require.NoError(t, discovery.Subscribe(transport))
+
+	wg := sync.WaitGroup{}
+	run := func(action func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < concurrencyLoops; i++ {
+				action(i)
+			}
+		}()
+	}
+	run(func(i int) {
+		// as received from the transport
+		discovery.Ingest(attributes...)
+		discovery.HandleMessage("homie/deviceID/node1/prop1", strconv.Itoa(i))
+	})
+	run(func(i int) { discovery.OnEvent(func(event Event) {}) })
+	run(func(i int) { discovery.SetRoot("homie") })
+	run(func(i int) { _ = discovery.Broadcast("alert", strconv.Itoa(i)) })
+	run(func(i int) { _ = discovery.Devices() })
+	wg.Wait()
+
+	assert.NotNil(t, discovery.Device("deviceID"))
+}
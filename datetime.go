@@ -0,0 +1,117 @@
+package homie
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDatetime converts the payload of a datetime property (ISO 8601) into a time
+func ParseDatetime(value string) (time.Time, error) {
+	datetime, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: '%s' is not an ISO 8601 datetime", ErrInvalidValue, value)
+	}
+	return datetime, nil
+}
+
+// FormatDatetime converts a time into the payload of a datetime property (ISO 8601)
+func FormatDatetime(datetime time.Time) string {
+	return datetime.Format(time.RFC3339Nano)
+}
+
+// units accepted in an ISO 8601 duration, in the order they must appear
+const durationUnits = "WDHMS"
+
+var durationUnitValues = [...]time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+
+// ParseDuration converts the payload of a duration property (ISO 8601 duration like PT1H30M) into a duration.
+// The units are weeks and days, then hours, minutes and seconds after the T: each unit can only be used once, in this order.
+// Years and months are not accepted as their duration is not fixed.
+func ParseDuration(value string) (time.Duration, error) {
+	invalid := fmt.Errorf("%w: '%s' is not an ISO 8601 duration", ErrInvalidValue, value)
+	input := value
+	negative := false
+	if strings.HasPrefix(input, "-") {
+		negative = true
+		input = input[1:]
+	}
+	if !strings.HasPrefix(input, "P") || len(input) < 3 {
+		return 0, invalid
+	}
+	input = input[1:]
+
+	var total float64
+	inTime := false
+	position := -1
+	number := ""
+	for _, char := range input {
+		switch {
+		case char >= '0' && char <= '9' || char == '.':
+			number += string(char)
+			continue
+		case char == 'T':
+			if inTime || number != "" {
+				return 0, invalid
+			}
+			inTime = true
+			continue
+		}
+		if number == "" {
+			return 0, invalid
+		}
+		amount, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return 0, invalid
+		}
+		number = ""
+		// each unit is used once, in order: W and D before T, then H, M and S
+		index := strings.IndexRune(durationUnits, char)
+		if index < 0 || index <= position || (index >= 2) != inTime {
+			return 0, invalid
+		}
+		position = index
+		total += amount * float64(durationUnitValues[index])
+	}
+	// math.MaxInt64 cannot be represented as a float64: the limit is 2^63
+	limit := float64(math.MaxInt64)
+	if number != "" || strings.HasSuffix(input, "T") || total > limit {
+		return 0, invalid
+	}
+	if negative {
+		return time.Duration(-math.Round(total)), nil
+	}
+	if total == limit {
+		return math.MaxInt64, nil
+	}
+	return time.Duration(math.Round(total)), nil
+}
+
+// FormatDuration converts a duration into the payload of a duration property (ISO 8601 duration like PT1H30M)
+func FormatDuration(duration time.Duration) string {
+	if duration == 0 {
+		return "PT0S"
+	}
+	builder := strings.Builder{}
+	// the absolute value of math.MinInt64 only fits in a uint64
+	remaining := uint64(duration)
+	if duration < 0 {
+		builder.WriteString("-")
+		remaining = -remaining
+	}
+	builder.WriteString("PT")
+	if hours := remaining / uint64(time.Hour); hours > 0 {
+		builder.WriteString(strconv.FormatUint(hours, 10) + "H")
+		remaining -= hours * uint64(time.Hour)
+	}
+	if minutes := remaining / uint64(time.Minute); minutes > 0 {
+		builder.WriteString(strconv.FormatUint(minutes, 10) + "M")
+		remaining -= minutes * uint64(time.Minute)
+	}
+	if remaining > 0 {
+		builder.WriteString(strconv.FormatFloat(time.Duration(remaining).Seconds(), 'f', -1, 64) + "S")
+	}
+	return builder.String()
+}
@@ -0,0 +1,112 @@
+package homie
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatAndParseDuration(t *testing.T) {
+	testData := []struct {
+		duration time.Duration
+		payload  string
+	}{
+		{0, "PT0S"},
+		{time.Second, "PT1S"},
+		{1500 * time.Millisecond, "PT1.5S"},
+		{90 * time.Minute, "PT1H30M"},
+		{26*time.Hour + 3*time.Second, "PT26H3S"},
+		{-2 * time.Minute, "-PT2M"},
+	}
+	for _, testItem := range testData {
+		t.Run(testItem.payload, func(t *testing.T) {
+			assert.Equal(t, testItem.payload, FormatDuration(testItem.duration))
+			duration, err := ParseDuration(testItem.payload)
+			assert.NoError(t, err)
+			assert.Equal(t, testItem.duration, duration)
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	testData := []struct {
+		payload  string
+		duration time.Duration
+	}{
+		{"P1D", 24 * time.Hour},
+		{"P1W", 7 * 24 * time.Hour},
+		{"P1DT12H", 36 * time.Hour},
+		{"PT0.25S", 250 * time.Millisecond},
+		{"PT1M30S", 90 * time.Second},
+	}
+	for _, testItem := range testData {
+		t.Run(testItem.payload, func(t *testing.T) {
+			duration, err := ParseDuration(testItem.payload)
+			assert.NoError(t, err)
+			assert.Equal(t, testItem.duration, duration)
+		})
+	}
+}
+
+func TestFormatDurationLimits(t *testing.T) {
+	for _, duration := range []time.Duration{math.MinInt64, math.MaxInt64} {
+		payload := FormatDuration(duration)
+		parsed, err := ParseDuration(payload)
+		assert.NoError(t, err, payload)
+		// a float64 cannot hold every nanosecond of the limits
+		assert.InDelta(t, float64(duration), float64(parsed), float64(time.Microsecond), payload)
+	}
+	assert.Equal(t, "-PT2562047H47M16.854775808S", FormatDuration(math.MinInt64))
+}
+
+func TestParseInvalidDuration(t *testing.T) {
+	testData := []string{
+		"", "P", "PT", "1H", "PT1", "P1H", "PT1D", "P1Y", "P1M", "P1DT", "PT1.2.3S", "PTH",
+		"PT1S2M", "PT1H1H", "P1D1W", "P1DT1S1H", "PT1M1M",
+	}
+	for _, payload := range testData {
+		t.Run(payload, func(t *testing.T) {
+			_, err := ParseDuration(payload)
+			assert.True(t, errors.Is(err, ErrInvalidValue))
+		})
+	}
+}
+
+func TestFormatAndParseDatetime(t *testing.T) {
+	datetime := time.Date(2021, 3, 13, 16, 29, 33, 0, time.UTC)
+	assert.Equal(t, "2021-03-13T16:29:33Z", FormatDatetime(datetime))
+
+	parsed, err := ParseDatetime("2021-03-13T17:29:33.5+01:00")
+	assert.NoError(t, err)
+	assert.True(t, datetime.Add(500*time.Millisecond).Equal(parsed))
+
+	_, err = ParseDatetime("13/03/2021")
+	assert.True(t, errors.Is(err, ErrInvalidValue))
+}
+
+func TestSetDatetimeAndDuration(t *testing.T) {
+	datetime := newProperty(nil, "test", "datetime", "datetime", TypeDatetime)
+	assert.NoError(t, datetime.TrySet(time.Date(2021, 3, 13, 16, 29, 33, 0, time.FixedZone("", 3600))))
+	assert.Equal(t, "2021-03-13T16:29:33+01:00", datetime.GetValue().Value)
+	assert.NoError(t, datetime.TrySet("2021-03-13T16:29:33Z"))
+	assert.Error(t, datetime.TrySet(10))
+
+	duration := newProperty(nil, "test", "duration", "duration", TypeDuration)
+	assert.NoError(t, duration.TrySet(2*time.Hour))
+	assert.Equal(t, "PT2H", duration.GetValue().Value)
+	assert.NoError(t, duration.TrySet("PT120M"))
+	assert.Equal(t, "PT2H", duration.GetValue().Value)
+	assert.Error(t, duration.TrySet(10))
+}
+
+func TestDurationSetCommand(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName")
+	device.AddNode("timer", "timer", "timer").AddProperty("delay", "delay", TypeDuration).Settable(true)
+
+	assert.NoError(t, device.HandleMessage("homie/deviceID/timer/delay/set", "PT90S"))
+	assert.Equal(t, "PT1M30S", device.Node("timer").Property("delay").GetValue().Value)
+	assert.True(t, errors.Is(device.HandleMessage("homie/deviceID/timer/delay/set", "90"), ErrInvalidValue))
+}
@@ -0,0 +1,72 @@
+package homie
+
+import (
+	"math"
+	"strconv"
+)
+
+// SetDeadband only publishes a new value of an integer or float property when it moves more than
+// this absolute amount away from the last published value. The value is always stored: see Value.
+//
+// A dead band of zero removes the filter. It is ignored on the other data types.
+func (p *Property) SetDeadband(band float64) *Property {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.deadband = math.Abs(band)
+	p.deadbandPercent = false
+	p.revision++
+	return p
+}
+
+// SetDeadbandPercent only publishes a new value of an integer or float property when it moves more than
+// this percentage of the last published value. The value is always stored: see Value.
+//
+// A dead band of zero removes the filter. It is ignored on the other data types.
+func (p *Property) SetDeadbandPercent(percent float64) *Property {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.deadband = math.Abs(percent)
+	p.deadbandPercent = true
+	p.revision++
+	return p
+}
+
+// Value returns the last value set on the property, published or not
+func (p *Property) Value() string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.value
+}
+
+// PublishedValue returns the last value published by the property.
+// It can be different from Value when a publish policy or a dead band is filtering the values.
+func (p *Property) PublishedValue() string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.publishedValue
+}
+
+// insideDeadband returns true when the payload is not far enough from the last published value.
+// The mutex must be held by the caller.
+func (p *Property) insideDeadband(payload string) bool {
+	if p.deadband == 0 || p.publishedAt.IsZero() || (p.dataType != TypeInteger && p.dataType != TypeFloat) {
+		return false
+	}
+	value, err := strconv.ParseFloat(payload, 64)
+	if err != nil {
+		return false
+	}
+	last, err := strconv.ParseFloat(p.publishedValue, 64)
+	if err != nil {
+		return false
+	}
+	band := p.deadband
+	if p.deadbandPercent {
+		band = math.Abs(last) * p.deadband / 100
+	}
+	return math.Abs(value-last) <= band
+}
@@ -0,0 +1,81 @@
+package homie
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadband(t *testing.T) {
+	values := make([]string, 0)
+	prop := NewDevice("deviceID", "deviceName").
+		OnSet(collectValues(&values)).
+		AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeFloat).SetDeadband(0.5)
+
+	for _, value := range []float64{20.0, 20.1, 19.6, 20.5, 20.6, 20.2, 20.0} {
+		prop.Set(value)
+	}
+	assert.Equal(t, []string{"20", "20.6", "20"}, values)
+	assert.Equal(t, "20", prop.Value())
+	assert.Equal(t, "20", prop.PublishedValue())
+
+	prop.Set(20.3)
+	assert.Equal(t, "20.3", prop.Value())
+	assert.Equal(t, "20", prop.PublishedValue())
+	assert.Equal(t, "20.3", prop.GetValue().Value)
+}
+
+func TestDeadbandPercent(t *testing.T) {
+	values := make([]string, 0)
+	prop := NewDevice("deviceID", "deviceName").
+		OnSet(collectValues(&values)).
+		AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeInteger).SetDeadbandPercent(10)
+
+	for _, value := range []int{100, 105, 110, 111, 121, 122, -1} {
+		prop.Set(value)
+	}
+	assert.Equal(t, []string{"100", "111", "-1"}, values)
+}
+
+func TestDeadbandIgnoredOnOtherTypes(t *testing.T) {
+	values := make([]string, 0)
+	prop := NewDevice("deviceID", "deviceName").
+		OnSet(collectValues(&values)).
+		AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeString).SetDeadband(10)
+
+	prop.Set("1").Set("2")
+	assert.Equal(t, []string{"1", "2"}, values)
+}
+
+func TestDeadbandWithHeartbeat(t *testing.T) {
+	clock := newFakeClock()
+	values := make([]string, 0)
+	prop := NewDevice("deviceID", "deviceName").
+		SetClock(clock).
+		OnSet(collectValues(&values)).
+		AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeFloat).
+		SetDeadband(1).
+		SetPublishPolicy(PublishOnChangeOrEvery(time.Minute))
+
+	prop.Set(10.0).Set(10.5)
+	clock.Advance(time.Minute)
+	prop.Set(10.2)
+	assert.Equal(t, []string{"10", "10.2"}, values)
+}
+
+func TestDeadbandDoesNotFilterCommands(t *testing.T) {
+	transport := newMockTransport()
+	device := NewDevice("deviceID", "deviceName").SetTransport(transport)
+	prop := device.AddNode("node1", "node1 name", "test1").AddProperty("prop1", "prop1 name", TypeFloat).Settable(true).SetDeadband(1)
+	prop.Set(10)
+	assert.NoError(t, device.Publish())
+	transport.messages = transport.messages[:0]
+
+	transport.send("homie/deviceID/node1/prop1/set", "10.5")
+	assert.Equal(t, []mockMessage{{"homie/deviceID/node1/prop1", QoS, true, "10.5"}}, transport.messages)
+}
@@ -0,0 +1,94 @@
+package homie
+
+import (
+	"encoding/json"
+	"hash/crc32"
+	"path"
+)
+
+// deviceDescription is the $description JSON document of a Homie 5 device.
+//
+// see documentation: https://homieiot.github.io/specification/#device-description
+type deviceDescription struct {
+	Homie   string                     `json:"homie"`
+	Version int64                      `json:"version"`
+	Name    string                     `json:"name,omitempty"`
+	Nodes   map[string]nodeDescription `json:"nodes,omitempty"`
+}
+
+type nodeDescription struct {
+	Name       string                         `json:"name,omitempty"`
+	Type       string                         `json:"type,omitempty"`
+	Properties map[string]propertyDescription `json:"properties,omitempty"`
+}
+
+type propertyDescription struct {
+	Name     string       `json:"name,omitempty"`
+	Datatype PropertyType `json:"datatype"`
+	Format   string       `json:"format,omitempty"`
+	Settable bool         `json:"settable,omitempty"`
+	Retained *bool        `json:"retained,omitempty"`
+	Unit     string       `json:"unit,omitempty"`
+}
+
+// GetDescription returns the $description JSON document of the device, as defined in Homie 5.
+//
+// The version field of the document is a checksum of the description: it changes every time the description changes.
+func (d *Device) GetDescription() string {
+	d.mutex.RLock()
+	description := deviceDescription{
+		Homie: "5.0",
+		Name:  d.name,
+	}
+	nodes := d.expandedNodes()
+	if len(nodes) > 0 {
+		description.Nodes = make(map[string]nodeDescription, len(nodes))
+	}
+	for nodeID, node := range nodes {
+		description.Nodes[nodeID] = node.getDescription()
+	}
+	d.mutex.RUnlock()
+
+	// maps are sorted by keys when encoded in JSON so the checksum is stable
+	document, _ := json.Marshal(description)
+	description.Version = int64(crc32.ChecksumIEEE(document))
+	document, _ = json.Marshal(description)
+	return string(document)
+}
+
+func (n *Node) getDescription() nodeDescription {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	description := nodeDescription{
+		Name: n.getName(),
+		Type: n.nodeType,
+	}
+	if len(n.properties) > 0 {
+		description.Properties = make(map[string]propertyDescription, len(n.properties))
+	}
+	for propertyID, prop := range n.properties {
+		definition := prop.definition()
+		propDesc := propertyDescription{
+			Name:     definition.name,
+			Datatype: definition.dataType,
+			Format:   definition.format,
+			Settable: definition.settable,
+			Unit:     definition.unit,
+		}
+		if !definition.retained {
+			// only publish the retained flag when it's not the default value
+			propDesc.Retained = &definition.retained
+		}
+		description.Properties[propertyID] = propDesc
+	}
+	return description
+}
+
+func (d *Device) getDescriptionAttributes() []TopicValuePair {
+	description := d.GetDescription()
+	return []TopicValuePair{
+		d.GetState(),
+		{path.Join(d.getPrefix(), attributeDescription), description},
+	}
+}
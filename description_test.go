@@ -0,0 +1,108 @@
+package homie
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersion5Attributes(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName").SetVersion(Version5)
+	device.
+		AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeBoolean).Settable(true).Set(true).Node().Device().
+		AddNode("node2", "node2 name", "test2").
+		AddProperty("prop2", "prop2 name", TypeFloat).SetUnit("°C").SetRange(-20, 50).SetRetained(false).Set(20.5)
+	attributes := device.GetHomieAttributes()
+	require.Len(t, attributes, 2)
+	assert.Equal(t, TopicValuePair{"homie/5/deviceID/$state", "init"}, attributes[0])
+	assert.Equal(t, "homie/5/deviceID/$description", attributes[1].Topic)
+
+	assert.ElementsMatch(t, device.GetValues(), []TopicValuePair{
+		{"homie/5/deviceID/node1/prop1", "true"},
+		{"homie/5/deviceID/node2/prop2", "20.5"},
+	})
+	assert.Equal(t, "homie/5/deviceID/$state", device.GetStateTopic())
+	assert.NotEmpty(t, device.GetPropertySetters()["homie/5/deviceID/node1/prop1/set"])
+}
+
+func TestVersion5Description(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName").SetVersion(Version5)
+	device.
+		AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeBoolean).Settable(true).Set(true).Node().Device().
+		AddNode("node2", "node2 name", "test2").
+		AddProperty("prop2", "prop2 name", TypeFloat).SetUnit("°C").SetRange(-20, 50).SetRetained(false).Set(20.5)
+	description := make(map[string]interface{})
+	err := json.Unmarshal([]byte(device.GetDescription()), &description)
+	require.NoError(t, err)
+
+	assert.NotZero(t, description["version"])
+	delete(description, "version")
+	assert.Equal(t, map[string]interface{}{
+		"homie": "5.0",
+		"name":  "deviceName",
+		"nodes": map[string]interface{}{
+			"node1": map[string]interface{}{
+				"name": "node1 name",
+				"type": "test1",
+				"properties": map[string]interface{}{
+					"prop1": map[string]interface{}{
+						"name":     "prop1 name",
+						"datatype": "boolean",
+						"settable": true,
+					},
+				},
+			},
+			"node2": map[string]interface{}{
+				"name": "node2 name",
+				"type": "test2",
+				"properties": map[string]interface{}{
+					"prop2": map[string]interface{}{
+						"name":     "prop2 name",
+						"datatype": "float",
+						"format":   "-20:50",
+						"unit":     "°C",
+						"retained": false,
+					},
+				},
+			},
+		},
+	}, description)
+}
+
+func TestVersion5DescriptionVersionChanges(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName").SetVersion(Version5)
+	device.AddNode("node1", "node1 name", "test1").AddProperty("prop1", "prop1 name", TypeBoolean)
+	description := device.GetDescription()
+	assert.Equal(t, description, device.GetDescription())
+
+	device.Node("node1").AddProperty("prop3", "prop3 name", TypeString)
+	assert.NotEqual(t, description, device.GetDescription())
+}
+
+func TestSwitchVersion(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName").SetVersion(Version5)
+	device.AddNode("node1", "node1 name", "test1").AddProperty("prop1", "prop1 name", TypeBoolean).Set(true)
+	device.SetVersion(Version4)
+	assert.Contains(t, device.GetHomieAttributes(), TopicValuePair{"homie/deviceID/$homie", "4.0.0"})
+	assert.Contains(t, device.GetValues(), TopicValuePair{"homie/deviceID/node1/prop1", "true"})
+}
+
+func TestVersion5Target(t *testing.T) {
+	values := make([]TopicValuePair, 0)
+	device := NewDevice("deviceID", "deviceName").SetVersion(Version5).OnSet(func(topic, value string, dataType PropertyType) {
+		values = append(values, TopicValuePair{topic, value})
+	})
+	device.AddNode("node2", "node2 name", "test2").AddProperty("prop2", "prop2 name", TypeFloat).SetRange(-20, 50)
+	device.Node("node2").Property("prop2").SetTarget(25).SetTarget("invalid")
+
+	assert.Equal(t, []TopicValuePair{{"homie/5/deviceID/node2/prop2/$target", "25"}}, values)
+	assert.Contains(t, device.GetValues(), TopicValuePair{"homie/5/deviceID/node2/prop2/$target", "25"})
+
+	// no target in Homie 4
+	device.SetVersion(Version4)
+	assert.NotContains(t, device.GetValues(), TopicValuePair{"homie/deviceID/node2/prop2/$target", "25"})
+}
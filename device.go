@@ -5,12 +5,23 @@ import (
 	"path"
 	"sort"
 	"strings"
+	"sync"
+	"time"
+)
+
+// Versions of the Homie convention supported by the library
+const (
+	Version3 = "3.0.1"
+	Version4 = "4.0.0"
+	Version5 = "5.0"
 )
 
 // Configuration variables
 var (
-	DefaultVersion = "4.0.0"
-	DefaultRoot    = "homie"
+	DefaultVersion        = Version4
+	DefaultRoot           = "homie"
+	DefaultImplementation = "go-homie"
+	DefaultStatsInterval  = 60 * time.Second
 )
 
 // DeviceState represents the current state of the device
@@ -26,15 +37,39 @@ const (
 	StateAlert        DeviceState = "alert"
 )
 
-// Device is the definition of your Homie device
+// Device is the definition of your Homie device.
+//
+// A device is safe for concurrent use: nodes, properties and values can be changed from different goroutines.
+// The callbacks are never called while holding a lock, so they can safely call back into the device.
 type Device struct {
-	prefix  string
-	version string
-	id      string
-	name    string
-	state   DeviceState
-	setter  Setter
-	nodes   map[string]*Node
+	mutex     sync.RWMutex
+	root      string
+	prefix    string
+	version   string
+	id        string
+	name      string
+	state     DeviceState
+	setter    Setter
+	transport Transport
+	nodes     map[string]*Node
+
+	extensions        []Extension
+	broadcastHandlers map[string]BroadcastHandler
+	stateHook         StateHook
+	batchSetter       BatchSetter
+	published         bool
+	policy            PublishPolicy
+	rateLimit         time.Duration
+	clock             Clock
+
+	// legacy attributes
+	started        time.Time
+	localIP        string
+	mac            string
+	fwName         string
+	fwVersion      string
+	implementation string
+	statsInterval  time.Duration
 }
 
 // NewDevice creates a homie device.
@@ -42,61 +77,296 @@ type Device struct {
 // ID is used to create topics: homie/<ID>/...
 // Name is the fullname of the device
 //
-// It will panic if ID cannot be used in a topic. You can check with IsValidID before calling NewDevice.
+// It will panic if ID cannot be used in a topic. You can check with IsValidID before calling NewDevice, or use NewDeviceE.
 //
 // see documentation: https://homieiot.github.io/specification/#topic-ids
 func NewDevice(id, name string) *Device {
 	if !IsValidID(id) {
 		panic(fmt.Sprintf("invalid device ID: '%s'", id))
 	}
-	return &Device{
-		prefix:  path.Join(DefaultRoot, id),
+	device := &Device{
+		root:    DefaultRoot,
 		version: DefaultVersion,
 		id:      id,
 		name:    name,
 		state:   StateInit,
 		nodes:   make(map[string]*Node, 0),
+		policy:  PublishAlways,
+		clock:   systemClock{},
+
+		started:        time.Now(),
+		implementation: DefaultImplementation,
+		statsInterval:  DefaultStatsInterval,
 	}
+	device.setPrefix()
+	return device
+}
+
+// NewDeviceE creates a homie device like NewDevice, but returns an error instead of panicking.
+// The ID must be valid and the name cannot be empty.
+//
+// The error is a *ValidationError listing all the problems found.
+func NewDeviceE(id, name string) (*Device, error) {
+	err := newValidationError(validateDefinition("device", id, name, false))
+	if err != nil {
+		return nil, err
+	}
+	return NewDevice(id, name), nil
 }
 
 // SetRoot changes the MQTT root topic: the default root is "homie".
 //
 // for more information: https://homieiot.github.io/specification/#base-topic
 func (d *Device) SetRoot(root string) *Device {
-	d.prefix = path.Join(root, d.id)
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.root = root
+	d.setPrefix()
 	return d
 }
 
-// SetState sets the state of the device
+// SetVersion selects the version of the Homie convention used to publish the device:
+// Version3, Version4 (default) or Version5.
+//
+// The same device definition is published using the layout of the selected version.
+func (d *Device) SetVersion(version string) *Device {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.version = version
+	d.setPrefix()
+	return d
+}
+
+// majorVersion returns the major version of the Homie convention used by the device.
+// The mutex must be held by the caller.
+func (d *Device) majorVersion() string {
+	return strings.SplitN(d.version, ".", 2)[0]
+}
+
+// setPrefix calculates the topic prefix of the device, nodes and properties.
+// The mutex must be held by the caller.
+func (d *Device) setPrefix() {
+	if d.majorVersion() == "5" {
+		// homie 5 includes the major version in the topics: homie/5/<ID>/...
+		d.prefix = path.Join(d.root, "5", d.id)
+	} else {
+		d.prefix = path.Join(d.root, d.id)
+	}
+	for _, node := range d.nodes {
+		node.setPrefix(d.prefix, d.majorVersion())
+	}
+}
+
+// getPrefix returns the topic prefix of the device
+func (d *Device) getPrefix() string {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return d.prefix
+}
+
+// SetState sets the state of the device.
+// An invalid transition is ignored: use TrySetState if you need the error.
 //
 // for more information about the device states: https://homieiot.github.io/specification/#device-lifecycle
 func (d *Device) SetState(state DeviceState) *Device {
-	d.state = state
-	if d.setter != nil {
-		d.setter(d.GetStateTopic(), string(state), TypeString)
-	}
+	_ = d.TrySetState(state)
 	return d
 }
 
+// send a retained device attribute to the callback and the transport
+func (d *Device) send(topic, value string) error {
+	d.mutex.RLock()
+	setter, transport := d.setter, d.transport
+	d.mutex.RUnlock()
+
+	if setter != nil {
+		setter(topic, value, TypeString)
+	}
+	if transport != nil {
+		return transport.Publish(topic, QoS, true, value)
+	}
+	return nil
+}
+
 // AddNode creates and add the node to the device
 //
 // ID is used to create topics: homie/device/<ID>/...
 // Name is the fullname of the node
 //
 // It will panic if ID cannot be used in a topic. You can check with IsValidID before calling the method.
+//
+// Adding a node to a device already published moves the device back to the "init" state.
 func (d *Device) AddNode(id, name, nodeType string) *Node {
+	node, _ := d.addNode(id, name, nodeType, false)
+	d.structureChanged()
+	return node
+}
+
+// TryAddNode creates and add the node to the device like AddNode, but returns an error instead of panicking.
+// The ID must be valid and not already used on the device, and the name cannot be empty.
+//
+// The error is a *ValidationError listing all the problems found.
+func (d *Device) TryAddNode(id, name, nodeType string) (*Node, error) {
+	node, err := d.addNode(id, name, nodeType, true)
+	if err != nil {
+		return nil, err
+	}
+	d.structureChanged()
+	return node, nil
+}
+
+// addNode creates and add the node to the device, after validating its definition if requested
+func (d *Device) addNode(id, name, nodeType string, validate bool) (*Node, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if validate {
+		_, duplicate := d.nodes[id]
+		err := newValidationError(validateDefinition("node", id, name, duplicate))
+		if err != nil {
+			return nil, err
+		}
+	}
 	node := newNode(d, d.prefix, id, name, nodeType)
 	d.nodes[id] = node
-	return node
+	return node, nil
+}
+
+// UniqueNodeID converts any string into a valid node ID not already used on the device (see ToUniqueID)
+func (d *Device) UniqueNodeID(s string) string {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return ToUniqueID(s, func(id string) bool {
+		_, found := d.nodes[id]
+		return found
+	})
+}
+
+// ID returns the ID of the device
+func (d *Device) ID() string {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return d.id
+}
+
+// Name returns the name of the device
+func (d *Device) Name() string {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return d.name
+}
+
+// Version returns the version of the Homie convention used by the device, like "4.0.0"
+func (d *Device) Version() string {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return d.version
 }
 
 // Node returns the node of that id
 func (d *Device) Node(id string) *Node {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
 	return d.nodes[id]
 }
 
-// GetHomieAttributes returns all attributes as a Topic/Value pair
+// Nodes returns the nodes of the device, sorted by ID.
+// A node array is returned once: use Index to access its elements.
+func (d *Device) Nodes() []*Node {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	nodes := make([]*Node, 0, len(d.nodes))
+	for _, node := range d.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].id < nodes[j].id
+	})
+	return nodes
+}
+
+// findProperty returns the property from a topic relative to the device: <nodeID>/<propertyID>.
+// It returns nil if no property matches the topic.
+func (d *Device) findProperty(topic string) *Property {
+	parts := strings.Split(topic, "/")
+	if len(parts) != 2 {
+		return nil
+	}
+	node := d.findNode(parts[0])
+	if node == nil {
+		return nil
+	}
+	return node.Property(parts[1])
+}
+
+// findNode returns the node from its ID as published: it can be an element of a node array
+func (d *Device) findNode(id string) *Node {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if node := d.nodes[id]; node != nil {
+		return node
+	}
+	for _, node := range d.nodes {
+		for _, instance := range node.instances {
+			if instance.ID() == id {
+				node.mutex.RLock()
+				node.syncInstances()
+				node.mutex.RUnlock()
+				return instance
+			}
+		}
+	}
+	return nil
+}
+
+// GetHomieAttributes returns all attributes as a Topic/Value pair.
+//
+// With Version3, the legacy $localip, $mac, $fw, $implementation and $stats attributes are also returned.
+// With Version5, the attributes are the $state and the $description JSON document.
 func (d *Device) GetHomieAttributes() []TopicValuePair {
+	d.mutex.RLock()
+	version := d.majorVersion()
+	d.mutex.RUnlock()
+
+	if version == "5" {
+		return d.getDescriptionAttributes()
+	}
+	attributes := d.getAttributes(version)
+	if version == "3" {
+		// the legacy attributes come after $homie, $name and $state
+		return insertAttributes(attributes, 3, d.getVersion3Attributes())
+	}
+	// the extensions are called without holding the lock: they can read the device attributes
+	d.mutex.RLock()
+	prefix, extensions := d.prefix, d.activeExtensions()
+	d.mutex.RUnlock()
+	// the extensions come after $homie, $name, $state and $nodes
+	return insertAttributes(attributes, 4, d.getExtensionsAttributes(prefix, extensions))
+}
+
+// insertAttributes returns a new list with the extra attributes inserted at this position
+func insertAttributes(attributes []TopicValuePair, position int, extra []TopicValuePair) []TopicValuePair {
+	all := make([]TopicValuePair, 0, len(attributes)+len(extra))
+	all = append(all, attributes[:position]...)
+	all = append(all, extra...)
+	return append(all, attributes[position:]...)
+}
+
+// getAttributes returns the device, nodes and properties attributes
+func (d *Device) getAttributes(version string) []TopicValuePair {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
 	attributes := make([]TopicValuePair, 0, len(d.nodes)*20)
 	attributes = append(attributes, TopicValuePair{path.Join(d.prefix, attributeHomieVersion), d.version})
 	attributes = append(attributes, TopicValuePair{path.Join(d.prefix, attributeName), d.name})
@@ -104,12 +374,19 @@ func (d *Device) GetHomieAttributes() []TopicValuePair {
 
 	nodes := ""
 	if d.nodes != nil && len(d.nodes) > 0 {
-		keys := make([]string, len(d.nodes))
+		keys := make([]string, 0, len(d.nodes))
 
-		i := 0
-		for key := range d.nodes {
-			keys[i] = key
-			i++
+		for key, node := range d.nodes {
+			switch {
+			case !node.array:
+				keys = append(keys, key)
+			case version == "3":
+				keys = append(keys, key+"[]")
+			default:
+				for _, instance := range node.instances {
+					keys = append(keys, instance.ID())
+				}
+			}
 		}
 		// this is not strictly necessary but it helps with the unit tests
 		sort.Slice(keys, func(i, j int) bool {
@@ -120,31 +397,38 @@ func (d *Device) GetHomieAttributes() []TopicValuePair {
 	}
 	attributes = append(attributes, TopicValuePair{path.Join(d.prefix, attributeNodes), nodes})
 
-	// empty extensions for now
-	attributes = append(attributes, TopicValuePair{path.Join(d.prefix, attributeExtensions), ""})
-
 	// now get properties from children
 	for _, node := range d.nodes {
-		attributes = append(attributes, node.getAttributes()...)
+		attributes = append(attributes, node.getAttributes(version)...)
 	}
 	return attributes
 }
 
 // GetState returns the device state as a Topic/Value pair
 func (d *Device) GetState() TopicValuePair {
-	return TopicValuePair{d.GetStateTopic(), string(d.state)}
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return TopicValuePair{path.Join(d.prefix, attributeState), string(d.state)}
 }
 
 // GetStateTopic returns the topic of the device state
 func (d *Device) GetStateTopic() string {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
 	return path.Join(d.prefix, attributeState)
 }
 
 // GetValues return the values of all properties
 func (d *Device) GetValues() []TopicValuePair {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	version := d.majorVersion()
 	attributes := make([]TopicValuePair, 0, len(d.nodes)*3)
 	for _, node := range d.nodes {
-		attributes = append(attributes, node.getValues()...)
+		attributes = append(attributes, node.getValues(version)...)
 	}
 	return attributes
 }
@@ -156,6 +440,9 @@ func (d *Device) GetValues() []TopicValuePair {
 //
 // see documentation: https://homieiot.github.io/specification/#property-command-topic
 func (d *Device) GetPropertySetters() map[string]*Property {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
 	properties := make(map[string]*Property, len(d.nodes)*3)
 	for _, node := range d.nodes {
 		props := node.getSetterProperties()
@@ -171,6 +458,134 @@ func (d *Device) GetPropertySetters() map[string]*Property {
 
 // OnSet adds a global callback when a property value is changed (via the Set method)
 func (d *Device) OnSet(setter Setter) *Device {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
 	d.setter = setter
 	return d
 }
+
+// SetTransport attaches a MQTT transport to the device.
+//
+// Once attached, the state and the property values are sent through the transport when they change.
+// Call Publish to send the full device description.
+//
+// If the transport implements WillSetter, the last will of the device is configured on the transport:
+// in that case the transport must be attached before connecting to the broker.
+func (d *Device) SetTransport(transport Transport) *Device {
+	d.mutex.Lock()
+	d.transport = transport
+	d.mutex.Unlock()
+
+	if willSetter, ok := transport.(WillSetter); ok {
+		willSetter.SetWill(d.LastWill())
+	}
+	return d
+}
+
+// LastWill returns the MQTT last will the client must configure before connecting to the broker:
+// the broker sets the state of the device to "lost" when the connection is lost.
+//
+// see documentation: https://homieiot.github.io/specification/#device-lifecycle
+func (d *Device) LastWill() Will {
+	return Will{
+		Topic:    d.GetStateTopic(),
+		Payload:  string(StateLost),
+		QoS:      QoS,
+		Retained: true,
+	}
+}
+
+// Disconnect gracefully publishes the "disconnected" state, then closes the transport
+func (d *Device) Disconnect() error {
+	d.mutex.RLock()
+	transport := d.transport
+	d.mutex.RUnlock()
+
+	if transport == nil {
+		return ErrNoTransport
+	}
+	err := d.TrySetState(StateDisconnected)
+	if err != nil {
+		return err
+	}
+	return transport.Close()
+}
+
+// Publish goes through the device lifecycle: it publishes the "init" state, then all the homie attributes
+// and the property values through the transport, subscribes to the property setters and the broadcast messages,
+// and finally publishes the "ready" state.
+//
+// Publish must be called again after adding nodes or properties to a device already published.
+//
+// Incoming messages are dispatched with HandleMessage.
+func (d *Device) Publish() error {
+	d.mutex.RLock()
+	transport := d.transport
+	d.mutex.RUnlock()
+
+	if transport == nil {
+		return ErrNoTransport
+	}
+	err := d.TrySetState(StateInit)
+	if err != nil {
+		return err
+	}
+	stateTopic := d.GetStateTopic()
+	for _, attribute := range d.GetHomieAttributes() {
+		if attribute.Topic == stateTopic {
+			// already sent
+			continue
+		}
+		err := transport.Publish(attribute.Topic, QoS, true, attribute.Value)
+		if err != nil {
+			return err
+		}
+	}
+	for _, prop := range d.expandedProperties() {
+		prop.mutex.RLock()
+		topic, value, retained := prop.prefix, prop.value, prop.retained
+		prop.mutex.RUnlock()
+		if value == "" {
+			// nothing to send yet
+			continue
+		}
+		err := transport.Publish(topic, QoS, retained, value)
+		if err != nil {
+			return err
+		}
+	}
+	subscriptions := make([]string, 0)
+	for topic := range d.GetPropertySetters() {
+		subscriptions = append(subscriptions, topic)
+	}
+	d.mutex.RLock()
+	subscriptions = append(subscriptions, d.getBroadcastSubscriptions()...)
+	d.mutex.RUnlock()
+	for _, topic := range subscriptions {
+		err := transport.Subscribe(topic, QoS, d.onMessage)
+		if err != nil {
+			return err
+		}
+	}
+	d.mutex.Lock()
+	d.published = true
+	d.mutex.Unlock()
+	return d.TrySetState(StateReady)
+}
+
+// expandedProperties returns the properties of the nodes as published in Homie 4 and 5
+func (d *Device) expandedProperties() []*Property {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	properties := make([]*Property, 0, len(d.nodes)*3)
+	for _, node := range d.expandedNodes() {
+		node.mutex.RLock()
+		for _, prop := range node.properties {
+			properties = append(properties, prop)
+		}
+		node.mutex.RUnlock()
+	}
+	return properties
+}
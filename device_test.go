@@ -115,3 +115,12 @@ func TestUndefinedNode(t *testing.T) {
 	node := device.Node("nodeID")
 	assert.Nil(t, node)
 }
+
+func TestChangeRootAfterAddingNodes(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName")
+	device.AddNode("node1", "node1 name", "test1").AddProperty("prop1", "prop1 name", TypeInteger).Set(1)
+	device.SetRoot("unit/test")
+
+	assert.Contains(t, device.GetHomieAttributes(), TopicValuePair{"unit/test/deviceID/node1/prop1/$name", "prop1 name"})
+	assert.Equal(t, []TopicValuePair{{"unit/test/deviceID/node1/prop1", "1"}}, device.GetValues())
+}
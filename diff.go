@@ -0,0 +1,113 @@
+package homie
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// DeviceDiff lists the differences between the homie attributes of two device definitions
+type DeviceDiff struct {
+	// Added contains the attributes only published by the new device
+	Added []TopicValuePair
+	// Changed contains the attributes published by both devices with a different value (the new value)
+	Changed []TopicValuePair
+	// Removed contains the attributes only published by the old device (the old value),
+	// and the value topics of the properties removed from the old device
+	Removed []TopicValuePair
+}
+
+// Diff compares the homie attributes of two device definitions: the old one (from) and the new one (to).
+// The device state and the stats values ($stats/uptime, $stats/signal...) change at runtime: they are not part
+// of the comparison. A nil device has no attributes.
+//
+// The values of the properties are not compared, but the value topic of a removed property is listed in Removed.
+//
+// Each list is sorted by topic.
+func Diff(from, to *Device) DeviceDiff {
+	oldAttributes := diffAttributes(from)
+	newAttributes := diffAttributes(to)
+
+	diff := DeviceDiff{
+		Added:   make([]TopicValuePair, 0),
+		Changed: make([]TopicValuePair, 0),
+		Removed: make([]TopicValuePair, 0),
+	}
+	for topic, value := range newAttributes {
+		oldValue, found := oldAttributes[topic]
+		if !found {
+			diff.Added = append(diff.Added, TopicValuePair{topic, value})
+		} else if oldValue != value {
+			diff.Changed = append(diff.Changed, TopicValuePair{topic, value})
+		}
+	}
+	for topic, value := range oldAttributes {
+		if _, found := newAttributes[topic]; !found {
+			diff.Removed = append(diff.Removed, TopicValuePair{topic, value})
+		}
+	}
+	newValues := diffValues(to)
+	for topic, value := range diffValues(from) {
+		if _, found := newValues[topic]; !found {
+			diff.Removed = append(diff.Removed, TopicValuePair{topic, value})
+		}
+	}
+	sortPairs(diff.Added)
+	sortPairs(diff.Changed)
+	sortPairs(diff.Removed)
+	return diff
+}
+
+// Empty returns true when both devices publish the same attributes
+func (d DeviceDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+// Messages returns the messages to publish to go from the old device to the new one:
+// the added and changed attributes, then an empty payload to clear each removed attribute.
+func (d DeviceDiff) Messages() []TopicValuePair {
+	messages := make([]TopicValuePair, 0, len(d.Added)+len(d.Changed)+len(d.Removed))
+	messages = append(messages, d.Added...)
+	messages = append(messages, d.Changed...)
+	for _, removed := range d.Removed {
+		messages = append(messages, TopicValuePair{removed.Topic, ""})
+	}
+	return messages
+}
+
+func diffAttributes(device *Device) map[string]string {
+	attributes := make(map[string]string)
+	if device == nil {
+		return attributes
+	}
+	stateTopic := device.GetStateTopic()
+	statsPrefix := path.Join(device.getPrefix(), attributeStats) + "/"
+	intervalTopic := statsPrefix + "interval"
+	for _, attribute := range device.GetHomieAttributes() {
+		if attribute.Topic == stateTopic {
+			continue
+		}
+		if strings.HasPrefix(attribute.Topic, statsPrefix) && attribute.Topic != intervalTopic {
+			continue
+		}
+		attributes[attribute.Topic] = attribute.Value
+	}
+	return attributes
+}
+
+func diffValues(device *Device) map[string]string {
+	values := make(map[string]string)
+	if device == nil {
+		return values
+	}
+	for _, value := range device.GetValues() {
+		values[value.Topic] = value.Value
+	}
+	return values
+}
+
+func sortPairs(pairs []TopicValuePair) {
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Topic < pairs[j].Topic
+	})
+}
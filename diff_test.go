@@ -0,0 +1,89 @@
+package homie
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffIdenticalDevices(t *testing.T) {
+	from := NewDevice("deviceID", "deviceName")
+	to := NewDevice("deviceID", "deviceName").SetState(StateReady)
+	for _, device := range []*Device{from, to} {
+		device.AddNode("node1", "node1 name", "test1").
+			AddProperty("prop1", "prop1 name", TypeInteger).SetUnit("W").Node().
+			AddProperty("prop2", "prop2 name", TypeString)
+	}
+	diff := Diff(from, to)
+	assert.True(t, diff.Empty())
+	assert.Empty(t, diff.Messages())
+}
+
+func TestDiffDevices(t *testing.T) {
+	from := NewDevice("deviceID", "deviceName")
+	to := NewDevice("deviceID", "deviceName")
+	for _, device := range []*Device{from, to} {
+		device.AddNode("node1", "node1 name", "test1").
+			AddProperty("prop1", "prop1 name", TypeInteger).SetUnit("W").Node().
+			AddProperty("prop2", "prop2 name", TypeString)
+	}
+	from.Node("node1").Property("prop2").Set("value")
+	to.Node("node1").Property("prop1").SetUnit("kW")
+	to.Node("node1").AddProperty("prop3", "prop3 name", TypeBoolean)
+	_, _ = to.Node("node1").RemoveProperty("prop2")
+
+	diff := Diff(from, to)
+	assert.False(t, diff.Empty())
+	assert.Equal(t, []TopicValuePair{
+		{"homie/deviceID/node1/prop3/$datatype", "boolean"},
+		{"homie/deviceID/node1/prop3/$name", "prop3 name"},
+	}, diff.Added)
+	assert.Equal(t, []TopicValuePair{
+		{"homie/deviceID/node1/$properties", "prop1,prop3"},
+		{"homie/deviceID/node1/prop1/$unit", "kW"},
+	}, diff.Changed)
+	assert.Equal(t, []TopicValuePair{
+		{"homie/deviceID/node1/prop2", "value"},
+		{"homie/deviceID/node1/prop2/$datatype", "string"},
+		{"homie/deviceID/node1/prop2/$name", "prop2 name"},
+	}, diff.Removed)
+
+	assert.Equal(t, []TopicValuePair{
+		{"homie/deviceID/node1/prop3/$datatype", "boolean"},
+		{"homie/deviceID/node1/prop3/$name", "prop3 name"},
+		{"homie/deviceID/node1/$properties", "prop1,prop3"},
+		{"homie/deviceID/node1/prop1/$unit", "kW"},
+		{"homie/deviceID/node1/prop2", ""},
+		{"homie/deviceID/node1/prop2/$datatype", ""},
+		{"homie/deviceID/node1/prop2/$name", ""},
+	}, diff.Messages())
+}
+
+func TestDiffWithNilDevice(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName")
+	device.AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeInteger).Set(1)
+
+	diff := Diff(nil, device)
+	assert.Len(t, diff.Added, len(device.GetHomieAttributes())-1)
+	assert.Empty(t, diff.Changed)
+	assert.Empty(t, diff.Removed)
+
+	diff = Diff(device, nil)
+	assert.Empty(t, diff.Added)
+	assert.Len(t, diff.Removed, len(device.GetHomieAttributes())-1+len(device.GetValues()))
+}
+
+func TestDiffIgnoresStats(t *testing.T) {
+	from := NewDevice("deviceID", "deviceName")
+	from.AddExtension(NewLegacyStats())
+	to := NewDevice("deviceID", "deviceName")
+	to.AddExtension(NewLegacyStats().AddProvider(StatSignal, func() (string, error) { return "80", nil }))
+	to.started = from.started.Add(-time.Hour)
+
+	assert.True(t, Diff(from, to).Empty())
+
+	to.SetStatsInterval(2 * time.Minute)
+	assert.Equal(t, []TopicValuePair{{"homie/deviceID/$stats/interval", "120"}}, Diff(from, to).Changed)
+}
@@ -0,0 +1,254 @@
+package homie
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// EventType is the type of a discovery event
+type EventType string
+
+// EventType
+const (
+	EventDeviceAdded   EventType = "added"
+	EventDeviceChanged EventType = "changed"
+	EventDeviceRemoved EventType = "removed"
+)
+
+// Event is sent by the discovery when a device is added, changed or removed
+type Event struct {
+	Type     EventType
+	DeviceID string
+	// Device is the last known definition of the device
+	Device *Device
+	// Topic and Value of the message which triggered the event
+	Topic string
+	Value string
+}
+
+// EventHandler is the signature of the callback receiving discovery events
+type EventHandler func(event Event)
+
+// Discovery builds the definition of the Homie devices from the messages published on the MQTT broker.
+// This is the controller side of the convention.
+//
+// A device is only reported once all its advertised nodes and properties attributes have been received.
+//
+// A discovery is safe for concurrent use: the messages can be received from the goroutine of the transport.
+// The event handler is called without holding the lock.
+//
+// see documentation: https://homieiot.github.io/specification/#discovery
+type Discovery struct {
+	root      string
+	devices   map[string]*discoveredDevice
+	handler   EventHandler
+	transport Transport
+	waiters   []*valueWaiter
+	mutex     sync.Mutex
+}
+
+type discoveredDevice struct {
+	topics map[string]string
+	device *Device
+}
+
+// NewDiscovery creates a discovery of the devices published under the default root topic
+func NewDiscovery() *Discovery {
+	return &Discovery{
+		root:    DefaultRoot,
+		devices: make(map[string]*discoveredDevice),
+	}
+}
+
+// SetRoot changes the MQTT root topic: the default root is "homie".
+func (d *Discovery) SetRoot(root string) *Discovery {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.root = root
+	return d
+}
+
+// OnEvent installs a callback receiving the events when a device is added, changed or removed
+func (d *Discovery) OnEvent(handler EventHandler) *Discovery {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.handler = handler
+	return d
+}
+
+// Subscribe to all the topics under the root topic. Incoming messages are sent to HandleMessage.
+func (d *Discovery) Subscribe(transport Transport) error {
+	d.mutex.Lock()
+	d.transport = transport
+	root := d.root
+	d.mutex.Unlock()
+	return transport.Subscribe(path.Join(root, "#"), QoS, d.HandleMessage)
+}
+
+// Ingest a list of Topic/Value pairs, typically the retained messages of the broker
+func (d *Discovery) Ingest(pairs ...TopicValuePair) {
+	for _, pair := range pairs {
+		d.HandleMessage(pair.Topic, pair.Value)
+	}
+}
+
+// HandleMessage processes a message received from the broker.
+// An empty payload clears the topic, and clearing the $homie attribute removes the device.
+func (d *Discovery) HandleMessage(topic, payload string) {
+	d.mutex.Lock()
+	event := d.update(topic, payload)
+	d.notifyWaiters(topic, payload)
+	handler := d.handler
+	d.mutex.Unlock()
+
+	if event != nil && handler != nil {
+		handler(*event)
+	}
+}
+
+// update the devices from the message, and returns the event to send (if any).
+// The mutex must be held by the caller.
+func (d *Discovery) update(topic, payload string) *Event {
+	if !strings.HasPrefix(topic, d.root+"/") {
+		return nil
+	}
+	parts := strings.SplitN(strings.TrimPrefix(topic, d.root+"/"), "/", 2)
+	if len(parts) != 2 || !IsValidID(parts[0]) {
+		return nil
+	}
+	deviceID, subTopic := parts[0], parts[1]
+	if strings.HasSuffix(subTopic, "/set") {
+		// this is a command, not a state
+		return nil
+	}
+	discovered := d.devices[deviceID]
+	if discovered == nil {
+		if payload == "" {
+			return nil
+		}
+		discovered = &discoveredDevice{topics: make(map[string]string)}
+		d.devices[deviceID] = discovered
+	}
+
+	if payload == "" {
+		delete(discovered.topics, subTopic)
+		if subTopic == attributeHomieVersion {
+			delete(d.devices, deviceID)
+			if discovered.device != nil {
+				return &Event{EventDeviceRemoved, deviceID, discovered.device, topic, payload}
+			}
+			return nil
+		}
+	} else {
+		discovered.topics[subTopic] = payload
+	}
+
+	// fast path for a new property value
+	if discovered.device != nil && !strings.Contains(subTopic, "$") {
+		if prop := discovered.device.findProperty(subTopic); prop != nil {
+			prop.mutex.Lock()
+			prop.value = payload
+			prop.mutex.Unlock()
+			return &Event{EventDeviceChanged, deviceID, discovered.device, topic, payload}
+		}
+	}
+
+	device := discovered.build(d.root, deviceID)
+	if device == nil {
+		return nil
+	}
+	eventType := EventDeviceChanged
+	if discovered.device == nil {
+		eventType = EventDeviceAdded
+	}
+	discovered.device = device
+	return &Event{eventType, deviceID, device, topic, payload}
+}
+
+// Device returns the definition of a discovered device, or nil if the device is unknown or not complete yet
+func (d *Discovery) Device(id string) *Device {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	discovered := d.devices[id]
+	if discovered == nil {
+		return nil
+	}
+	return discovered.device
+}
+
+// Devices returns the definition of all the complete devices, sorted by ID
+func (d *Discovery) Devices() []*Device {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	devices := make([]*Device, 0, len(d.devices))
+	for _, discovered := range d.devices {
+		if discovered.device != nil {
+			devices = append(devices, discovered.device)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].id < devices[j].id
+	})
+	return devices
+}
+
+// build returns the device definition, or nil if some attributes are still missing
+func (dd *discoveredDevice) build(root, id string) *Device {
+	for _, attribute := range []string{attributeHomieVersion, attributeName, attributeState, attributeNodes} {
+		if _, found := dd.topics[attribute]; !found {
+			return nil
+		}
+	}
+	device := NewDevice(id, dd.topics[attributeName]).SetRoot(root)
+	device.version = dd.topics[attributeHomieVersion]
+
+	for _, nodeID := range splitList(dd.topics[attributeNodes]) {
+		if !IsValidID(nodeID) {
+			return nil
+		}
+		name, foundName := dd.topics[path.Join(nodeID, attributeName)]
+		properties, foundProperties := dd.topics[path.Join(nodeID, attributeProperties)]
+		if !foundName || !foundProperties {
+			return nil
+		}
+		node := device.AddNode(nodeID, name, dd.topics[path.Join(nodeID, attributeType)])
+
+		for _, propertyID := range splitList(properties) {
+			if !IsValidID(propertyID) {
+				return nil
+			}
+			prefix := path.Join(nodeID, propertyID)
+			name, foundName := dd.topics[path.Join(prefix, attributeName)]
+			dataType, foundDataType := dd.topics[path.Join(prefix, attributeDatatype)]
+			format, foundFormat := dd.topics[path.Join(prefix, attributeFormat)]
+			if !foundName || !foundDataType {
+				return nil
+			}
+			if !foundFormat && (PropertyType(dataType) == TypeEnum || PropertyType(dataType) == TypeColor) {
+				return nil
+			}
+			prop := node.AddProperty(propertyID, name, PropertyType(dataType)).
+				SetFormat(format).
+				SetUnit(dd.topics[path.Join(prefix, attributeUnit)]).
+				Settable(dd.topics[path.Join(prefix, attributeSettable)] == "true").
+				SetRetained(dd.topics[path.Join(prefix, attributeRetained)] != "false")
+			prop.value = dd.topics[prefix]
+		}
+	}
+	device.state = DeviceState(dd.topics[attributeState])
+	return device
+}
+
+// splitList returns the items of a comma separated list
+func splitList(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
@@ -0,0 +1,163 @@
+package homie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscoverDevice(t *testing.T) {
+	source := NewDevice("deviceID", "deviceName")
+	source.
+		AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeBoolean).Settable(true).Set(true).Node().
+		AddProperty("prop2", "prop2 name", TypeEnum).SetEnumValues("low", "high").SetRetained(false).Node().Device().
+		AddNode("node2", "node2 name", "test2").
+		AddProperty("prop3", "prop3 name", TypeFloat).SetRange(0, 100).SetUnit("%").Set(10.5)
+	discovery := NewDiscovery()
+	discovery.Ingest(source.GetHomieAttributes()...)
+	discovery.Ingest(source.GetValues()...)
+
+	device := discovery.Device("deviceID")
+	require.NotNil(t, device)
+	assert.ElementsMatch(t, source.GetHomieAttributes(), device.GetHomieAttributes())
+	assert.ElementsMatch(t, source.GetValues(), device.GetValues())
+	assert.Len(t, discovery.Devices(), 1)
+}
+
+func TestDiscoverDeviceWithCustomRoot(t *testing.T) {
+	source := NewDevice("deviceID", "deviceName").SetRoot("unit/test")
+	source.AddNode("node1", "node1 name", "test1").AddProperty("prop1", "prop1 name", TypeBoolean)
+	discovery := NewDiscovery()
+	discovery.Ingest(source.GetHomieAttributes()...)
+	assert.Nil(t, discovery.Device("deviceID"))
+
+	discovery.SetRoot("unit/test")
+	discovery.Ingest(source.GetHomieAttributes()...)
+	assert.NotNil(t, discovery.Device("deviceID"))
+}
+
+func TestDiscoveryEvents(t *testing.T) {
+	events := make([]Event, 0)
+	discovery := NewDiscovery().OnEvent(func(event Event) {
+		events = append(events, event)
+	})
+	source := NewDevice("deviceID", "deviceName")
+	source.
+		AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeBoolean).Settable(true).Node().Device().
+		AddNode("node2", "node2 name", "test2").
+		AddProperty("prop3", "prop3 name", TypeFloat)
+	missing := TopicValuePair{"homie/deviceID/node2/prop3/$datatype", "float"}
+	for _, attribute := range source.GetHomieAttributes() {
+		if attribute != missing {
+			discovery.Ingest(attribute)
+		}
+	}
+	assert.Empty(t, events)
+	assert.Nil(t, discovery.Device("deviceID"))
+	assert.Empty(t, discovery.Devices())
+
+	discovery.Ingest(missing)
+	require.Len(t, events, 1)
+	assert.Equal(t, EventDeviceAdded, events[0].Type)
+	assert.Equal(t, "deviceID", events[0].DeviceID)
+	assert.NotNil(t, events[0].Device)
+
+	discovery.HandleMessage("homie/deviceID/node2/prop3", "20")
+	require.Len(t, events, 2)
+	assert.Equal(t, EventDeviceChanged, events[1].Type)
+	assert.Equal(t, "20", discovery.Device("deviceID").Node("node2").Property("prop3").GetValue().Value)
+
+	discovery.HandleMessage("homie/deviceID/$state", "ready")
+	require.Len(t, events, 3)
+	assert.Equal(t, EventDeviceChanged, events[2].Type)
+	assert.Equal(t, "ready", discovery.Device("deviceID").GetState().Value)
+
+	// commands are ignored
+	discovery.HandleMessage("homie/deviceID/node1/prop1/set", "false")
+	assert.Len(t, events, 3)
+
+	discovery.HandleMessage("homie/deviceID/$homie", "")
+	require.Len(t, events, 4)
+	assert.Equal(t, EventDeviceRemoved, events[3].Type)
+	assert.Nil(t, discovery.Device("deviceID"))
+}
+
+func TestDiscoveryIncompleteProperty(t *testing.T) {
+	discovery := NewDiscovery()
+	discovery.Ingest(
+		TopicValuePair{"homie/deviceID/$homie", "4.0.0"},
+		TopicValuePair{"homie/deviceID/$name", "deviceName"},
+		TopicValuePair{"homie/deviceID/$state", "ready"},
+		TopicValuePair{"homie/deviceID/$nodes", "node1"},
+		TopicValuePair{"homie/deviceID/node1/$name", "node1 name"},
+		TopicValuePair{"homie/deviceID/node1/$properties", "prop1"},
+		TopicValuePair{"homie/deviceID/node1/prop1/$name", "prop1 name"},
+		TopicValuePair{"homie/deviceID/node1/prop1/$datatype", "enum"},
+	)
+	// enum needs a $format
+	assert.Nil(t, discovery.Device("deviceID"))
+
+	discovery.HandleMessage("homie/deviceID/node1/prop1/$format", "a,b")
+	assert.NotNil(t, discovery.Device("deviceID"))
+}
+
+func TestDiscoverySubscribe(t *testing.T) {
+	transport := newMockTransport()
+	discovery := NewDiscovery()
+	err := discovery.Subscribe(transport)
+	assert.NoError(t, err)
+	assert.NotNil(t, transport.subscriptions["homie/#"])
+}
+
+func TestDiscoveredDeviceTree(t *testing.T) {
+	source := NewDevice("deviceID", "deviceName")
+	source.
+		AddNode("node2", "node2 name", "test2").
+		AddProperty("prop3", "prop3 name", TypeFloat).SetRange(0, 100).SetUnit("%").SetRetained(false).Node().Device().
+		AddNode("node1", "node1 name", "test1").
+		AddProperty("prop2", "prop2 name", TypeString).Node().
+		AddProperty("prop1", "prop1 name", TypeBoolean).Settable(true).Set(true)
+
+	var device *Device
+	discovery := NewDiscovery().OnEvent(func(event Event) {
+		device = event.Device
+	})
+	discovery.Ingest(source.GetHomieAttributes()...)
+	discovery.Ingest(source.GetValues()...)
+	require.NotNil(t, device)
+
+	type property struct {
+		id, name, unit, format, value string
+		dataType                      PropertyType
+		settable, retained            bool
+	}
+	tree := make(map[string][]property)
+	nodes := make([]string, 0)
+	assert.Equal(t, "deviceID", device.ID())
+	assert.Equal(t, "deviceName", device.Name())
+	assert.Equal(t, "4.0.0", device.Version())
+	for _, node := range device.Nodes() {
+		nodes = append(nodes, node.ID()+" "+node.Name()+" "+node.Type())
+		for _, prop := range node.Properties() {
+			format, err := prop.Format()
+			require.NoError(t, err)
+			tree[node.ID()] = append(tree[node.ID()], property{
+				prop.ID(), prop.Name(), prop.Unit(), format.String(), prop.Value(),
+				prop.DataType(), prop.IsSettable(), prop.IsRetained(),
+			})
+		}
+	}
+	assert.Equal(t, []string{"node1 node1 name test1", "node2 node2 name test2"}, nodes)
+	assert.Equal(t, map[string][]property{
+		"node1": {
+			{"prop1", "prop1 name", "", "", "true", TypeBoolean, true, true},
+			{"prop2", "prop2 name", "", "", "", TypeString, false, true},
+		},
+		"node2": {
+			{"prop3", "prop3 name", "%", "0:100", "", TypeFloat, false, false},
+		},
+	}, tree)
+}
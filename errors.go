@@ -0,0 +1,115 @@
+package homie
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors returned by the library
+var (
+	ErrNoTransport       = errors.New("no transport attached to the device")
+	ErrUnknownTopic      = errors.New("unknown topic")
+	ErrNotSettable       = errors.New("property is not settable")
+	ErrUnknownNode       = errors.New("unknown node")
+	ErrUnknownProperty   = errors.New("unknown property")
+	ErrInvalidValue      = errors.New("invalid value")
+	ErrInvalidFormat     = errors.New("invalid format")
+	ErrInvalidID         = errors.New("invalid ID")
+	ErrInvalidTransition = errors.New("invalid state transition")
+	ErrDuplicateID       = errors.New("duplicate ID")
+	ErrMissingName       = errors.New("missing name")
+	ErrInvalidInterval   = errors.New("invalid interval")
+	ErrArrayProperty     = errors.New("property of a node array")
+	ErrInactiveExtension = errors.New("extension not active on the device")
+)
+
+// CommandError is returned when an incoming set command cannot be processed.
+// Use errors.Is to check for the cause (ErrUnknownTopic, ErrNotSettable, ErrInvalidValue, or the error returned by a command handler)
+type CommandError struct {
+	Topic   string
+	Payload string
+	Err     error
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("command on topic '%s' with payload '%s': %v", e.Topic, e.Payload, e.Err)
+}
+
+// Unwrap returns the cause of the error
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned when a device definition is invalid: it contains all the problems found.
+// Use errors.Is to check for a cause (ErrInvalidID, ErrDuplicateID or ErrMissingName)
+type ValidationError struct {
+	Errors []error
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return "invalid device definition: " + strings.Join(messages, "; ")
+}
+
+// Is returns true if any of the problems matches the target
+func (e *ValidationError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// BatchError is returned when some values of a batch are invalid: it contains all the problems found.
+// Use errors.Is to check for a cause (ErrUnknownProperty or ErrInvalidValue)
+type BatchError struct {
+	Errors []error
+}
+
+func (e *BatchError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return "invalid batch: " + strings.Join(messages, "; ")
+}
+
+// Is returns true if any of the problems matches the target
+func (e *BatchError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateDefinition returns all the problems found in the definition of a device, node or property
+func validateDefinition(kind, id, name string, duplicate bool) []error {
+	errs := make([]error, 0)
+	if strings.HasPrefix(id, "$") {
+		errs = append(errs, fmt.Errorf("%w: %s ID '%s' cannot start with '$'", ErrInvalidID, kind, id))
+	} else if !IsValidID(id) {
+		errs = append(errs, fmt.Errorf("%w: %s ID '%s'", ErrInvalidID, kind, id))
+	}
+	if duplicate {
+		errs = append(errs, fmt.Errorf("%w: %s ID '%s' is already used", ErrDuplicateID, kind, id))
+	}
+	if name == "" {
+		errs = append(errs, fmt.Errorf("%w: %s '%s' has no name", ErrMissingName, kind, id))
+	}
+	return errs
+}
+
+// newValidationError returns nil if there's no error
+func newValidationError(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: errs}
+}
@@ -12,7 +12,7 @@ func main() {
 	device := homie.
 		NewDevice("raspberry-pi", "Raspberry PI agent").
 		AddNode("bme280", "BME280 on GPIO", "bme280").
-		AddProperty("temperature", "Temperature", homie.TypeFloat).SetUnit("°C").Node().
+		AddProperty("temperature", "Temperature", homie.TypeFloat).SetUnit("°C").SetDeadband(0.1).Node().
 		AddProperty("pressure", "Pressure", homie.TypeFloat).SetUnit("hPa").Node().
 		AddProperty("humidity", "Humidity", homie.TypeFloat).SetUnit("%").Node().
 		Device()
@@ -26,10 +26,12 @@ func main() {
 	device.OnSet(onSet)
 
 	for i := 0; i <= 3; i++ {
-		// new values will be published (to the console)
-		device.Node("bme280").Property("temperature").Set(28 + i)
-		device.Node("bme280").Property("humidity").Set(40 + i*10)
-		device.Node("bme280").Property("pressure").Set(998 + i)
+		// new values will be published (to the console), as one group
+		_ = device.Node("bme280").Update(map[string]interface{}{
+			"temperature": 28 + i,
+			"humidity":    40 + i*10,
+			"pressure":    998 + i,
+		})
 
 		device.SetState(homie.StateSleeping)
 		time.Sleep(3 * time.Second)
@@ -38,13 +40,7 @@ func main() {
 }
 
 func onSet(topic, value string, dataType homie.PropertyType) {
-	if value == "<nil>" {
-		value = ""
-	}
-	if value == "" && dataType != homie.TypeString {
-		// don't send a blank string on anything else than a string data type
-		return
-	}
+	// the value has already been validated against the data type of the property
 	publish(topic, value)
 }
 
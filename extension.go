@@ -0,0 +1,67 @@
+package homie
+
+import (
+	"path"
+	"strings"
+)
+
+// Extension adds attributes to a Homie device. An extension is registered with Device.AddExtension.
+//
+// see documentation: https://homieiot.github.io/specification/#extensions
+type Extension interface {
+	// ID is the unique identifier of the extension, like "org.homie.legacy-stats"
+	ID() string
+	// Version of the extension, like "0.1.1"
+	Version() string
+	// HomieVersions is the list of supported Homie versions, like "4.x"
+	HomieVersions() []string
+	// Attributes returns the Topic/Value pairs added to the device.
+	// The topics are relative to the device topic, like "$stats/interval".
+	//
+	// The device can be read with its accessors (ID, Name, Nodes...), but Attributes must not call
+	// GetHomieAttributes: it calls Attributes again and never returns.
+	Attributes(device *Device) []TopicValuePair
+}
+
+// AddExtension registers an extension on the device.
+//
+// Extensions are only published when the device uses a Homie version supported by the extension.
+func (d *Device) AddExtension(extension Extension) *Device {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.extensions = append(d.extensions, extension)
+	return d
+}
+
+// activeExtensions returns the extensions supporting the Homie version of the device.
+// The mutex must be held by the caller.
+func (d *Device) activeExtensions() []Extension {
+	extensions := make([]Extension, 0, len(d.extensions))
+	for _, extension := range d.extensions {
+		for _, version := range extension.HomieVersions() {
+			if strings.SplitN(version, ".", 2)[0] == d.majorVersion() {
+				extensions = append(extensions, extension)
+				break
+			}
+		}
+	}
+	return extensions
+}
+
+// getExtensionsAttributes returns the $extensions attribute followed by the attributes added by the extensions.
+// The mutex must not be held by the caller: the extensions read the attributes of the device.
+func (d *Device) getExtensionsAttributes(prefix string, extensions []Extension) []TopicValuePair {
+	list := make([]string, len(extensions))
+	for i, extension := range extensions {
+		list[i] = extension.ID() + ":" + extension.Version() + ":[" + strings.Join(extension.HomieVersions(), ";") + "]"
+	}
+	attributes := make([]TopicValuePair, 0, len(extensions)*4+1)
+	attributes = append(attributes, TopicValuePair{path.Join(prefix, attributeExtensions), strings.Join(list, ",")})
+	for _, extension := range extensions {
+		for _, attribute := range extension.Attributes(d) {
+			attributes = append(attributes, TopicValuePair{path.Join(prefix, attribute.Topic), attribute.Value})
+		}
+	}
+	return attributes
+}
@@ -0,0 +1,67 @@
+package homie
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testExtension struct{}
+
+func (e testExtension) ID() string {
+	return "org.test.unit"
+}
+
+func (e testExtension) Version() string {
+	return "1.0.0"
+}
+
+func (e testExtension) HomieVersions() []string {
+	return []string{"4.x", "5.x"}
+}
+
+func (e testExtension) Attributes(device *Device) []TopicValuePair {
+	return []TopicValuePair{{"$test", device.Name() + ":" + strconv.Itoa(len(device.Nodes()))}}
+}
+
+func TestCustomExtension(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName").AddExtension(testExtension{})
+	device.AddNode("node1", "node1 name", "test1")
+	attributes := device.GetHomieAttributes()
+	assert.Contains(t, attributes, TopicValuePair{"homie/deviceID/$extensions", "org.test.unit:1.0.0:[4.x;5.x]"})
+	assert.Contains(t, attributes, TopicValuePair{"homie/deviceID/$test", "deviceName:1"})
+}
+
+func TestLegacyExtensions(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName").
+		SetLocalIP("192.168.0.10").
+		SetMAC("DE:AD:BE:EF:FE:ED").
+		SetFirmware("firmware", "1.0.0").
+		SetStatsInterval(30 * time.Second).
+		AddExtension(NewLegacyStats()).
+		AddExtension(NewLegacyFirmware())
+	device.started = time.Now().Add(-90 * time.Second)
+
+	assert.ElementsMatch(t, device.GetHomieAttributes(), []TopicValuePair{
+		{"homie/deviceID/$homie", "4.0.0"},
+		{"homie/deviceID/$name", "deviceName"},
+		{"homie/deviceID/$state", "init"},
+		{"homie/deviceID/$nodes", ""},
+		{"homie/deviceID/$extensions", "org.homie.legacy-stats:0.1.1:[4.x],org.homie.legacy-firmware:0.1.1:[4.x]"},
+		{"homie/deviceID/$stats/interval", "30"},
+		{"homie/deviceID/$stats/uptime", "90"},
+		{"homie/deviceID/$localip", "192.168.0.10"},
+		{"homie/deviceID/$mac", "DE:AD:BE:EF:FE:ED"},
+		{"homie/deviceID/$fw/name", "firmware"},
+		{"homie/deviceID/$fw/version", "1.0.0"},
+	})
+}
+
+func TestExtensionNotSupportedByVersion(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName").AddExtension(NewLegacyStats()).SetVersion(Version3)
+	attributes := device.GetHomieAttributes()
+	assert.NotContains(t, attributes, TopicValuePair{"homie/deviceID/$extensions", "org.homie.legacy-stats:0.1.1:[4.x]"})
+	assert.Empty(t, device.activeExtensions())
+}
@@ -0,0 +1,209 @@
+package homie
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ColorFormat is the format of a color property
+type ColorFormat string
+
+// ColorFormat
+const (
+	ColorRGB ColorFormat = "rgb"
+	ColorHSV ColorFormat = "hsv"
+)
+
+// Format is the structured representation of the $format attribute of a property:
+//   - integer and float: an optional range "from:to" where both bounds are optional
+//   - enum: a comma separated list of values
+//   - color: "rgb" or "hsv"
+//
+// see documentation: https://homieiot.github.io/specification/#properties
+type Format struct {
+	DataType PropertyType
+	HasMin   bool
+	Min      float64
+	HasMax   bool
+	Max      float64
+	Enum     []string
+	Color    ColorFormat
+	raw      string
+}
+
+// ParseFormat converts the $format attribute of a property of that data type into a Format
+func ParseFormat(dataType PropertyType, format string) (Format, error) {
+	result := Format{DataType: dataType, raw: format}
+	switch dataType {
+	case TypeInteger, TypeFloat:
+		if format == "" {
+			return result, nil
+		}
+		bounds := strings.Split(format, ":")
+		if len(bounds) != 2 {
+			return result, fmt.Errorf("%w: '%s' is not a range", ErrInvalidFormat, format)
+		}
+		var err error
+		if bounds[0] != "" {
+			result.HasMin = true
+			result.Min, err = parseBound(dataType, bounds[0])
+			if err != nil {
+				return result, err
+			}
+		}
+		if bounds[1] != "" {
+			result.HasMax = true
+			result.Max, err = parseBound(dataType, bounds[1])
+			if err != nil {
+				return result, err
+			}
+		}
+		if result.HasMin && result.HasMax && result.Min > result.Max {
+			return result, fmt.Errorf("%w: '%s' is not a valid range", ErrInvalidFormat, format)
+		}
+
+	case TypeEnum:
+		if format == "" {
+			return result, fmt.Errorf("%w: enum format cannot be empty", ErrInvalidFormat)
+		}
+		result.Enum = strings.Split(format, ",")
+
+	case TypeColor:
+		switch ColorFormat(format) {
+		case ColorRGB, ColorHSV:
+			result.Color = ColorFormat(format)
+		default:
+			return result, fmt.Errorf("%w: '%s' is not a color format", ErrInvalidFormat, format)
+		}
+	}
+	return result, nil
+}
+
+func parseBound(dataType PropertyType, bound string) (float64, error) {
+	if dataType == TypeInteger {
+		number, err := strconv.ParseInt(bound, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: '%s' is not an integer", ErrInvalidFormat, bound)
+		}
+		return float64(number), nil
+	}
+	number, err := strconv.ParseFloat(bound, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, fmt.Errorf("%w: '%s' is not a float", ErrInvalidFormat, bound)
+	}
+	return number, nil
+}
+
+// String returns the $format attribute value
+func (f Format) String() string {
+	switch f.DataType {
+	case TypeInteger, TypeFloat:
+		if !f.HasMin && !f.HasMax {
+			return ""
+		}
+		min, max := "", ""
+		if f.HasMin {
+			min = strconv.FormatFloat(f.Min, 'f', -1, 64)
+		}
+		if f.HasMax {
+			max = strconv.FormatFloat(f.Max, 'f', -1, 64)
+		}
+		return min + ":" + max
+
+	case TypeEnum:
+		return strings.Join(f.Enum, ",")
+
+	case TypeColor:
+		return string(f.Color)
+	}
+	return f.raw
+}
+
+// Contains returns true if the value is an item of an enum format
+func (f Format) Contains(value string) bool {
+	for _, item := range f.Enum {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the payload is valid for the data type and the format.
+//
+// see documentation: https://homieiot.github.io/specification/#payload
+func (f Format) Validate(value string) error {
+	switch f.DataType {
+	case TypeInteger:
+		number, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: '%s' is not an integer", ErrInvalidValue, value)
+		}
+		return f.validateRange(float64(number))
+
+	case TypeFloat:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			return fmt.Errorf("%w: '%s' is not a float", ErrInvalidValue, value)
+		}
+		return f.validateRange(number)
+
+	case TypeBoolean:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("%w: '%s' is not a boolean", ErrInvalidValue, value)
+		}
+
+	case TypeEnum:
+		if !f.Contains(value) {
+			return fmt.Errorf("%w: '%s' is not one of '%s'", ErrInvalidValue, value, f.String())
+		}
+
+	case TypeColor:
+		return f.validateColor(value)
+
+	case TypeDatetime:
+		_, err := ParseDatetime(value)
+		return err
+
+	case TypeDuration:
+		_, err := ParseDuration(value)
+		return err
+	}
+	return nil
+}
+
+func (f Format) validateRange(number float64) error {
+	if f.HasMin && number < f.Min {
+		return fmt.Errorf("%w: %v is lower than %v", ErrInvalidValue, number, f.Min)
+	}
+	if f.HasMax && number > f.Max {
+		return fmt.Errorf("%w: %v is greater than %v", ErrInvalidValue, number, f.Max)
+	}
+	return nil
+}
+
+// validateColor checks the value is a "x,y,z" triplet for the color format
+func (f Format) validateColor(value string) error {
+	var limits []int64
+	switch f.Color {
+	case ColorRGB:
+		limits = []int64{255, 255, 255}
+	case ColorHSV:
+		limits = []int64{360, 100, 100}
+	default:
+		return fmt.Errorf("%w: unknown color format '%s'", ErrInvalidValue, f.Color)
+	}
+	components := strings.Split(value, ",")
+	if len(components) != len(limits) {
+		return fmt.Errorf("%w: '%s' is not a %s color", ErrInvalidValue, value, f.Color)
+	}
+	for i, component := range components {
+		number, err := strconv.ParseInt(component, 10, 64)
+		if err != nil || number < 0 || number > limits[i] {
+			return fmt.Errorf("%w: '%s' is not a %s color", ErrInvalidValue, value, f.Color)
+		}
+	}
+	return nil
+}
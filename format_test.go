@@ -0,0 +1,118 @@
+package homie
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFormat(t *testing.T) {
+	testData := []struct {
+		dataType PropertyType
+		format   string
+		expected Format
+	}{
+		{TypeInteger, "", Format{DataType: TypeInteger}},
+		{TypeInteger, "0:100", Format{DataType: TypeInteger, HasMin: true, Min: 0, HasMax: true, Max: 100}},
+		{TypeInteger, "-10:", Format{DataType: TypeInteger, HasMin: true, Min: -10}},
+		{TypeFloat, ":1.5", Format{DataType: TypeFloat, HasMax: true, Max: 1.5}},
+		{TypeEnum, "low,high", Format{DataType: TypeEnum, Enum: []string{"low", "high"}}},
+		{TypeColor, "rgb", Format{DataType: TypeColor, Color: ColorRGB}},
+		{TypeColor, "hsv", Format{DataType: TypeColor, Color: ColorHSV}},
+	}
+	for _, testItem := range testData {
+		t.Run(string(testItem.dataType)+" "+testItem.format, func(t *testing.T) {
+			format, err := ParseFormat(testItem.dataType, testItem.format)
+			assert.NoError(t, err)
+			testItem.expected.raw = testItem.format
+			assert.Equal(t, testItem.expected, format)
+			assert.Equal(t, testItem.format, format.String())
+		})
+	}
+}
+
+func TestParseInvalidFormat(t *testing.T) {
+	testData := []struct {
+		dataType PropertyType
+		format   string
+	}{
+		{TypeInteger, "10"},
+		{TypeInteger, "1.5:10"},
+		{TypeInteger, "10:0"},
+		{TypeFloat, "a:b"},
+		{TypeFloat, "0:NaN"},
+		{TypeEnum, ""},
+		{TypeColor, ""},
+		{TypeColor, "cmyk"},
+	}
+	for _, testItem := range testData {
+		t.Run(string(testItem.dataType)+" "+testItem.format, func(t *testing.T) {
+			_, err := ParseFormat(testItem.dataType, testItem.format)
+			assert.True(t, errors.Is(err, ErrInvalidFormat))
+		})
+	}
+}
+
+func TestPropertyFormatBuilders(t *testing.T) {
+	testData := []struct {
+		property *Property
+		format   string
+	}{
+		{newProperty(nil, "test", "id", "name", TypeInteger).SetRange(0, 100), "0:100"},
+		{newProperty(nil, "test", "id", "name", TypeInteger).SetRange(-0.5, 10.5), "0:10"},
+		{newProperty(nil, "test", "id", "name", TypeFloat).SetRange(-1.5, 2.25), "-1.5:2.25"},
+		{newProperty(nil, "test", "id", "name", TypeEnum).SetEnumValues("low", "medium", "high"), "low,medium,high"},
+		{newProperty(nil, "test", "id", "name", TypeColor).SetColorFormat(ColorHSV), "hsv"},
+	}
+	for _, testItem := range testData {
+		t.Run(testItem.format, func(t *testing.T) {
+			assert.Contains(t, testItem.property.getAttributes(), TopicValuePair{"test/id/$format", testItem.format})
+			format, err := testItem.property.Format()
+			assert.NoError(t, err)
+			assert.Equal(t, testItem.format, format.String())
+		})
+	}
+}
+
+func TestIntegerRangeIsEnforced(t *testing.T) {
+	prop := newProperty(nil, "test", "id", "name", TypeInteger).SetRange(0.5, 10)
+	assert.NoError(t, prop.TrySet(1))
+	assert.True(t, errors.Is(prop.TrySet(0), ErrInvalidValue))
+	assert.True(t, errors.Is(prop.TrySet(100), ErrInvalidValue))
+}
+
+func TestInvalidRangeIsIgnored(t *testing.T) {
+	testData := []struct {
+		name     string
+		property *Property
+		min, max float64
+		format   string
+	}{
+		{"empty integer range", newProperty(nil, "test", "id", "name", TypeInteger).SetRange(0, 10), 0.5, 0.7, "0:10"},
+		{"inverted range", newProperty(nil, "test", "id", "name", TypeFloat).SetRange(0, 10), 10, 0, "0:10"},
+		{"not a number", newProperty(nil, "test", "id", "name", TypeFloat), math.NaN(), 10, ""},
+		{"enum", newProperty(nil, "test", "id", "name", TypeEnum).SetEnumValues("low", "high"), 0, 10, "low,high"},
+		{"string", newProperty(nil, "test", "id", "name", TypeString), 0, 10, ""},
+	}
+	for _, testItem := range testData {
+		t.Run(testItem.name, func(t *testing.T) {
+			revision := testItem.property.revision
+			testItem.property.SetRange(testItem.min, testItem.max)
+			assert.Equal(t, revision, testItem.property.revision)
+			assert.Equal(t, testItem.format, testItem.property.format)
+		})
+	}
+
+	// the property still accepts values
+	prop := newProperty(nil, "test", "id", "name", TypeInteger).SetRange(0, 10).SetRange(0.5, 0.7)
+	assert.NoError(t, prop.TrySet(5))
+}
+
+func TestFormatContains(t *testing.T) {
+	format, err := ParseFormat(TypeEnum, "low,medium,high")
+	assert.NoError(t, err)
+	assert.True(t, format.Contains("medium"))
+	assert.False(t, format.Contains("none"))
+}
@@ -1,6 +1,9 @@
 package homie
 
-import "strings"
+import (
+	"strconv"
+	"strings"
+)
 
 // IsValidID checks the ID can be used in a topic.
 //
@@ -16,3 +19,72 @@ func IsValidID(id string) bool {
 	}
 	return true
 }
+
+// transliterations of the most common accented latin characters (in lowercase)
+var transliterations = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae",
+	'ç': "c", 'ć': "c", 'č': "c",
+	'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ł': "l", 'ľ': "l",
+	'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o",
+	'œ': "oe",
+	'ř': "r",
+	'ß': "ss", 'ś': "s", 'š': "s", 'ş': "s",
+	'ť': "t", 'ţ': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ý': "y", 'ÿ': "y",
+	'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// ToValidID converts any string into an ID that can be used in a topic:
+// accented characters are transliterated, the result is in lowercase,
+// invalid characters are replaced by a "-", and leading or trailing "-" are removed.
+//
+// It returns "id" if nothing is left from the original string.
+//
+//	ToValidID("Living Room / Sensor #2") == "living-room-sensor-2"
+func ToValidID(s string) string {
+	builder := strings.Builder{}
+	dash := false
+	for _, char := range strings.ToLower(s) {
+		replacement := ""
+		if (char >= 'a' && char <= 'z') || (char >= '0' && char <= '9') {
+			replacement = string(char)
+		} else if transliteration, found := transliterations[char]; found {
+			replacement = transliteration
+		}
+		if replacement == "" {
+			dash = true
+			continue
+		}
+		if dash && builder.Len() > 0 {
+			builder.WriteString("-")
+		}
+		dash = false
+		builder.WriteString(replacement)
+	}
+	if builder.Len() == 0 {
+		return "id"
+	}
+	return builder.String()
+}
+
+// ToUniqueID converts any string into a valid ID like ToValidID,
+// then adds a suffix "-2", "-3", etc. until the ID is not already used.
+func ToUniqueID(s string, used func(id string) bool) string {
+	id := ToValidID(s)
+	if !used(id) {
+		return id
+	}
+	for i := 2; ; i++ {
+		candidate := id + "-" + strconv.Itoa(i)
+		if !used(candidate) {
+			return candidate
+		}
+	}
+}
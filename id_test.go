@@ -27,3 +27,43 @@ func TestInvalidID(t *testing.T) {
 		})
 	}
 }
+
+func TestToValidID(t *testing.T) {
+	testData := []struct {
+		input    string
+		expected string
+	}{
+		{"valid-id", "valid-id"},
+		{"Living Room / Sensor #2", "living-room-sensor-2"},
+		{"DE:AD:BE:EF:FE:ED", "de-ad-be-ef-fe-ed"},
+		{"Température extérieure", "temperature-exterieure"},
+		{"Größe", "grosse"},
+		{"  --leading and trailing--  ", "leading-and-trailing"},
+		{"not_valid", "not-valid"},
+		{"CO₂ sensor", "co-sensor"},
+		{"", "id"},
+		{"###", "id"},
+	}
+	for _, testItem := range testData {
+		t.Run(testItem.input, func(t *testing.T) {
+			id := ToValidID(testItem.input)
+			assert.Equal(t, testItem.expected, id)
+			assert.True(t, IsValidID(id))
+		})
+	}
+}
+
+func TestToUniqueID(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName")
+	device.AddNode("sensor", "sensor", "sensor")
+	device.AddNode("sensor-2", "sensor", "sensor")
+
+	id := device.UniqueNodeID("Sensor")
+	assert.Equal(t, "sensor-3", id)
+	assert.True(t, IsValidID(id))
+	assert.Equal(t, "other", device.UniqueNodeID("Other"))
+
+	node := device.Node("sensor")
+	node.AddProperty("temperature", "temperature", TypeFloat)
+	assert.Equal(t, "temperature-2", node.UniquePropertyID("Temperature"))
+}
@@ -0,0 +1,52 @@
+package homie
+
+import (
+	"path"
+)
+
+// LegacyFirmware is the official extension publishing the Homie 3 firmware attributes on a Homie 4 device.
+// The values are defined on the device with SetLocalIP, SetMAC and SetFirmware.
+//
+// see documentation: https://homieiot.github.io/extensions/
+type LegacyFirmware struct{}
+
+// NewLegacyFirmware creates the org.homie.legacy-firmware extension
+func NewLegacyFirmware() *LegacyFirmware {
+	return &LegacyFirmware{}
+}
+
+// ID is "org.homie.legacy-firmware"
+func (e *LegacyFirmware) ID() string {
+	return "org.homie.legacy-firmware"
+}
+
+// Version of the extension
+func (e *LegacyFirmware) Version() string {
+	return "0.1.1"
+}
+
+// HomieVersions supported by the extension
+func (e *LegacyFirmware) HomieVersions() []string {
+	return []string{"4.x"}
+}
+
+// Attributes returns $localip, $mac, $fw/name and $fw/version.
+// An attribute which is not defined on the device is skipped: an empty retained payload would delete the topic.
+func (e *LegacyFirmware) Attributes(device *Device) []TopicValuePair {
+	device.mutex.RLock()
+	defer device.mutex.RUnlock()
+
+	all := []TopicValuePair{
+		{attributeLocalIP, device.localIP},
+		{attributeMAC, device.mac},
+		{path.Join(attributeFirmware, "name"), device.fwName},
+		{path.Join(attributeFirmware, "version"), device.fwVersion},
+	}
+	attributes := make([]TopicValuePair, 0, len(all))
+	for _, attribute := range all {
+		if attribute.Value != "" {
+			attributes = append(attributes, attribute)
+		}
+	}
+	return attributes
+}
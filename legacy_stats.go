@@ -0,0 +1,176 @@
+package homie
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Names of the stats defined by the legacy stats extension
+const (
+	StatUptime   = "uptime"
+	StatSignal   = "signal"
+	StatCPUTemp  = "cputemp"
+	StatCPULoad  = "cpuload"
+	StatBattery  = "battery"
+	StatFreeHeap = "freeheap"
+	StatSupply   = "supply"
+)
+
+// StatProvider returns the current value of a stat
+type StatProvider func() (string, error)
+
+// LegacyStats is the official extension publishing the Homie 3 stats attributes on a Homie 4 device.
+// The interval is defined on the device with SetStatsInterval.
+//
+// The uptime is calculated from the creation of the device. Other stats are added with AddProvider.
+//
+// see documentation: https://homieiot.github.io/extensions/
+type LegacyStats struct {
+	providers map[string]StatProvider
+	stop      chan struct{}
+	mutex     sync.Mutex
+}
+
+// NewLegacyStats creates the org.homie.legacy-stats extension
+func NewLegacyStats() *LegacyStats {
+	return &LegacyStats{
+		providers: make(map[string]StatProvider),
+	}
+}
+
+// ID is "org.homie.legacy-stats"
+func (e *LegacyStats) ID() string {
+	return "org.homie.legacy-stats"
+}
+
+// Version of the extension
+func (e *LegacyStats) Version() string {
+	return "0.1.1"
+}
+
+// HomieVersions supported by the extension
+func (e *LegacyStats) HomieVersions() []string {
+	return []string{"4.x"}
+}
+
+// AddProvider registers a provider for the stat published as $stats/<name>.
+// A provider registered for the uptime replaces the default uptime of the device.
+func (e *LegacyStats) AddProvider(name string, provider StatProvider) *LegacyStats {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.providers[name] = provider
+	return e
+}
+
+// Attributes returns $stats/interval, $stats/uptime and the stats from the providers.
+// A stat is skipped when its provider returns an error.
+func (e *LegacyStats) Attributes(device *Device) []TopicValuePair {
+	device.mutex.RLock()
+	interval := device.statsInterval
+	device.mutex.RUnlock()
+
+	// the providers are called without holding the lock: they can call AddProvider
+	e.mutex.Lock()
+	providers := make(map[string]StatProvider, len(e.providers))
+	for name, provider := range e.providers {
+		providers[name] = provider
+	}
+	e.mutex.Unlock()
+
+	attributes := make([]TopicValuePair, 0, len(providers)+2)
+	attributes = append(attributes, TopicValuePair{path.Join(attributeStats, "interval"), strconv.FormatInt(int64(interval/time.Second), 10)})
+	if _, found := providers[StatUptime]; !found {
+		attributes = append(attributes, TopicValuePair{path.Join(attributeStats, StatUptime), strconv.FormatInt(int64(device.Uptime()/time.Second), 10)})
+	}
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, err := providers[name]()
+		if err != nil {
+			continue
+		}
+		attributes = append(attributes, TopicValuePair{path.Join(attributeStats, name), value})
+	}
+	return attributes
+}
+
+// Start publishing the stats of the device every $stats/interval, through the device callback and transport.
+// Calling Start again restarts the publication with the current interval of the device.
+//
+// It returns an error if the interval of the device is not positive, or if the extension was not added to the device
+// for its Homie version (Homie 3 publishes the stats without an extension, but they are read from this one):
+// the stats are not published. The publication also pauses while the device uses another Homie version.
+func (e *LegacyStats) Start(device *Device) error {
+	device.mutex.RLock()
+	interval := device.statsInterval
+	device.mutex.RUnlock()
+
+	if interval <= 0 {
+		return fmt.Errorf("%w: %v", ErrInvalidInterval, interval)
+	}
+	if !e.activeOn(device) {
+		return fmt.Errorf("%w: %s", ErrInactiveExtension, e.ID())
+	}
+	e.Stop()
+
+	e.mutex.Lock()
+	stop := make(chan struct{})
+	e.stop = stop
+	e.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if !e.activeOn(device) {
+					continue
+				}
+				for _, attribute := range e.Attributes(device) {
+					_ = device.send(path.Join(device.getPrefix(), attribute.Topic), attribute.Value)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// activeOn returns true if the extension was added to the device and is published with its Homie version
+func (e *LegacyStats) activeOn(device *Device) bool {
+	device.mutex.RLock()
+	defer device.mutex.RUnlock()
+
+	extensions := device.activeExtensions()
+	if device.majorVersion() == "3" {
+		extensions = device.extensions
+	}
+	for _, extension := range extensions {
+		if extension == Extension(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stop publishing the stats
+func (e *LegacyStats) Stop() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.stop != nil {
+		close(e.stop)
+		e.stop = nil
+	}
+}
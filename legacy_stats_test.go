@@ -0,0 +1,107 @@
+package homie
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLegacyStatsProviders(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName").SetStatsInterval(10 * time.Second)
+	device.started = time.Now().Add(-90 * time.Second)
+	stats := NewLegacyStats().
+		AddProvider(StatSignal, func() (string, error) { return "80", nil }).
+		AddProvider(StatBattery, func() (string, error) { return "", errors.New("no battery") })
+
+	assert.Equal(t, []TopicValuePair{
+		{"$stats/interval", "10"},
+		{"$stats/uptime", "90"},
+		{"$stats/signal", "80"},
+	}, stats.Attributes(device))
+}
+
+func TestLegacyStatsUptimeProvider(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName")
+	stats := NewLegacyStats().AddProvider(StatUptime, func() (string, error) { return "1000", nil })
+
+	assert.Equal(t, []TopicValuePair{
+		{"$stats/interval", "60"},
+		{"$stats/uptime", "1000"},
+	}, stats.Attributes(device))
+}
+
+func TestLegacyStatsPublishing(t *testing.T) {
+	received := make(chan TopicValuePair, 10)
+	device := NewDevice("deviceID", "deviceName").
+		SetStatsInterval(10 * time.Millisecond).
+		OnSet(func(topic, value string, dataType PropertyType) {
+			received <- TopicValuePair{topic, value}
+		})
+	stats := NewLegacyStats().AddProvider(StatSignal, func() (string, error) { return "80", nil })
+	device.AddExtension(stats)
+
+	require.NoError(t, stats.Start(device))
+	defer stats.Stop()
+
+	topics := make(map[string]bool)
+	timeout := time.After(time.Second)
+	for len(topics) < 3 {
+		select {
+		case pair := <-received:
+			topics[pair.Topic] = true
+		case <-timeout:
+			t.Fatal("timeout waiting for stats")
+		}
+	}
+	assert.Equal(t, map[string]bool{
+		"homie/deviceID/$stats/interval": true,
+		"homie/deviceID/$stats/uptime":   true,
+		"homie/deviceID/$stats/signal":   true,
+	}, topics)
+}
+
+func TestLegacyStatsInvalidInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		device := NewDevice("deviceID", "deviceName").SetStatsInterval(interval)
+		stats := NewLegacyStats()
+		err := stats.Start(device)
+		assert.True(t, errors.Is(err, ErrInvalidInterval))
+		stats.Stop()
+	}
+}
+
+func TestLegacyStatsInactive(t *testing.T) {
+	stats := NewLegacyStats()
+	err := stats.Start(NewDevice("deviceID", "deviceName"))
+	assert.True(t, errors.Is(err, ErrInactiveExtension))
+
+	err = stats.Start(NewDevice("deviceID", "deviceName").SetVersion(Version5).AddExtension(stats))
+	assert.True(t, errors.Is(err, ErrInactiveExtension))
+
+	require.NoError(t, stats.Start(NewDevice("deviceID", "deviceName").SetVersion(Version3).AddExtension(stats)))
+	stats.Stop()
+}
+
+func TestLegacyStatsProviderAddingProvider(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName")
+	stats := NewLegacyStats()
+	stats.AddProvider(StatSignal, func() (string, error) {
+		stats.AddProvider(StatBattery, func() (string, error) { return "90", nil })
+		return "80", nil
+	})
+
+	done := make(chan []TopicValuePair)
+	go func() {
+		done <- stats.Attributes(device)
+	}()
+	select {
+	case attributes := <-done:
+		assert.Contains(t, attributes, TopicValuePair{"$stats/signal", "80"})
+	case <-time.After(time.Second):
+		t.Fatal("deadlock calling the providers")
+	}
+	assert.Contains(t, stats.Attributes(device), TopicValuePair{"$stats/battery", "90"})
+}
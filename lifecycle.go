@@ -0,0 +1,90 @@
+package homie
+
+import (
+	"fmt"
+	"path"
+)
+
+// StateHook is the signature of the callback receiving the state transitions of the device
+type StateHook func(from, to DeviceState)
+
+// transitions lists the states a device can move to, from each state.
+// The "lost" state is never published by the device itself: it's the last will set by the broker.
+//
+// see documentation: https://homieiot.github.io/specification/#device-lifecycle
+var transitions = map[DeviceState][]DeviceState{
+	StateInit:         {StateInit, StateReady, StateSleeping, StateAlert, StateDisconnected},
+	StateReady:        {StateInit, StateReady, StateSleeping, StateAlert, StateDisconnected},
+	StateSleeping:     {StateInit, StateReady, StateSleeping, StateAlert, StateDisconnected},
+	StateAlert:        {StateInit, StateReady, StateSleeping, StateAlert, StateDisconnected},
+	StateDisconnected: {StateInit, StateDisconnected},
+	StateLost:         {StateInit},
+}
+
+// TrySetState moves the device to a new state, and publishes it.
+//
+// It returns an error if the transition is not allowed:
+//   - the device cannot publish the "lost" state: this is the job of the MQTT last will
+//   - after "disconnected" or "lost", the device must go through "init" again
+//   - "alert" doesn't exist in Homie 5
+//   - when a transport is attached, "ready" can only be published after the description (see Publish)
+//
+// see documentation: https://homieiot.github.io/specification/#device-lifecycle
+func (d *Device) TrySetState(state DeviceState) error {
+	d.mutex.Lock()
+	if !d.canTransition(d.state, state) {
+		d.mutex.Unlock()
+		return fmt.Errorf("%w: from '%s' to '%s'", ErrInvalidTransition, d.state, state)
+	}
+	if state == StateReady && d.transport != nil && !d.published {
+		d.mutex.Unlock()
+		return fmt.Errorf("%w: description must be published before '%s'", ErrInvalidTransition, state)
+	}
+	from := d.state
+	d.state = state
+	topic := path.Join(d.prefix, attributeState)
+	hook := d.stateHook
+	d.mutex.Unlock()
+
+	err := d.send(topic, string(state))
+	if hook != nil {
+		hook(from, state)
+	}
+	return err
+}
+
+// OnStateChange installs a callback receiving all the state transitions of the device
+func (d *Device) OnStateChange(hook StateHook) *Device {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.stateHook = hook
+	return d
+}
+
+// canTransition checks the transition is allowed. The mutex must be held by the caller.
+func (d *Device) canTransition(from, to DeviceState) bool {
+	if to == StateAlert && d.majorVersion() == "5" {
+		return false
+	}
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// structureChanged moves the device back to "init" when nodes or properties are added at runtime:
+// the description needs to be published again
+func (d *Device) structureChanged() {
+	d.mutex.Lock()
+	d.published = false
+	state := d.state
+	d.mutex.Unlock()
+
+	switch state {
+	case StateReady, StateSleeping, StateAlert:
+		_ = d.TrySetState(StateInit)
+	}
+}
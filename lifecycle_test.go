@@ -0,0 +1,96 @@
+package homie
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateTransitions(t *testing.T) {
+	testData := []struct {
+		from  DeviceState
+		to    DeviceState
+		valid bool
+	}{
+		{StateInit, StateReady, true},
+		{StateInit, StateSleeping, true},
+		{StateReady, StateAlert, true},
+		{StateReady, StateInit, true},
+		{StateSleeping, StateReady, true},
+		{StateAlert, StateReady, true},
+		{StateReady, StateDisconnected, true},
+		{StateDisconnected, StateInit, true},
+		{StateLost, StateInit, true},
+		{StateInit, StateLost, false},
+		{StateReady, StateLost, false},
+		{StateLost, StateReady, false},
+		{StateLost, StateDisconnected, false},
+		{StateDisconnected, StateReady, false},
+		{StateReady, DeviceState("unknown"), false},
+	}
+	for _, testItem := range testData {
+		t.Run(string(testItem.from)+" to "+string(testItem.to), func(t *testing.T) {
+			device := NewDevice("deviceID", "deviceName")
+			device.state = testItem.from
+			err := device.TrySetState(testItem.to)
+			if testItem.valid {
+				assert.NoError(t, err)
+				assert.Equal(t, testItem.to, device.state)
+			} else {
+				assert.True(t, errors.Is(err, ErrInvalidTransition))
+				assert.Equal(t, testItem.from, device.state)
+			}
+		})
+	}
+}
+
+func TestNoAlertStateInVersion5(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName").SetVersion(Version5)
+	assert.True(t, errors.Is(device.TrySetState(StateAlert), ErrInvalidTransition))
+}
+
+func TestReadyBeforeDescription(t *testing.T) {
+	transport := newMockTransport()
+	device := NewDevice("deviceID", "deviceName").SetTransport(transport)
+	assert.True(t, errors.Is(device.TrySetState(StateReady), ErrInvalidTransition))
+	assert.Empty(t, transport.messages)
+
+	require.NoError(t, device.Publish())
+	assert.Equal(t, StateReady, device.state)
+}
+
+func TestStateHook(t *testing.T) {
+	transitions := make([]string, 0)
+	transport := newMockTransport()
+	device := NewDevice("deviceID", "deviceName").
+		SetTransport(transport).
+		OnStateChange(func(from, to DeviceState) {
+			transitions = append(transitions, string(from)+">"+string(to))
+		})
+	require.NoError(t, device.Publish())
+	device.SetState(StateSleeping)
+	device.SetState(StateLost)
+
+	assert.Equal(t, []string{"init>init", "init>ready", "ready>sleeping"}, transitions)
+}
+
+func TestStructureChangeAfterPublish(t *testing.T) {
+	transport := newMockTransport()
+	device := NewDevice("deviceID", "deviceName").SetTransport(transport)
+	node := device.AddNode("node1", "node1 name", "test1")
+	require.NoError(t, device.Publish())
+	require.Equal(t, StateReady, device.state)
+
+	transport.messages = transport.messages[:0]
+	node.AddProperty("prop1", "prop1 name", TypeInteger)
+	assert.Equal(t, StateInit, device.state)
+	assert.Equal(t, []mockMessage{{"homie/deviceID/$state", QoS, true, "init"}}, transport.messages)
+
+	// ready is refused until the new description is published
+	assert.Error(t, device.TrySetState(StateReady))
+	require.NoError(t, device.Publish())
+	assert.Equal(t, StateReady, device.state)
+	assert.Contains(t, transport.pairs(), TopicValuePair{"homie/deviceID/node1/prop1/$name", "prop1 name"})
+}
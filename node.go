@@ -5,16 +5,27 @@ import (
 	"path"
 	"sort"
 	"strings"
+	"sync"
 )
 
-// Node definition
+// Node definition.
+//
+// A node is safe for concurrent use.
 type Node struct {
+	mutex      sync.RWMutex
 	device     *Device
 	prefix     string
 	id         string
 	name       string
 	nodeType   string
 	properties map[string]*Property
+
+	// node array
+	array     bool
+	from      int
+	instances []*Node
+	parent    *Node
+	index     int
 }
 
 func newNode(device *Device, prefix, id, name, nodeType string) *Node {
@@ -37,10 +48,74 @@ func newNode(device *Device, prefix, id, name, nodeType string) *Node {
 // Name is the fullname of the property
 //
 // It will panic if ID cannot be used in a topic. You can check with IsValidID before calling the method.
+//
+// Adding a property to a device already published moves the device back to the "init" state.
 func (n *Node) AddProperty(id, name string, propertyType PropertyType) *Property {
+	prop, _ := n.addProperty(id, name, propertyType, false)
+	n.structureChanged()
+	return prop
+}
+
+// UniquePropertyID converts any string into a valid property ID not already used on the node (see ToUniqueID)
+func (n *Node) UniquePropertyID(s string) string {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	return ToUniqueID(s, func(id string) bool {
+		_, found := n.properties[id]
+		return found
+	})
+}
+
+// TryAddProperty creates and add the property to the node like AddProperty, but returns an error instead of panicking.
+// The ID must be valid and not already used on the node, and the name cannot be empty.
+//
+// The error is a *ValidationError listing all the problems found.
+func (n *Node) TryAddProperty(id, name string, propertyType PropertyType) (*Property, error) {
+	prop, err := n.addProperty(id, name, propertyType, true)
+	if err != nil {
+		return nil, err
+	}
+	n.structureChanged()
+	return prop, nil
+}
+
+// addProperty creates and add the property to the node, after validating its definition if requested
+func (n *Node) addProperty(id, name string, propertyType PropertyType, validate bool) (*Property, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if validate {
+		_, duplicate := n.properties[id]
+		err := newValidationError(validateDefinition("property", id, name, duplicate))
+		if err != nil {
+			return nil, err
+		}
+	}
 	prop := newProperty(n, n.prefix, id, name, propertyType)
 	n.properties[id] = prop
-	return prop
+	return prop, nil
+}
+
+// structureChanged tells the device a property was added or removed
+func (n *Node) structureChanged() {
+	if n.device != nil {
+		n.device.structureChanged()
+	}
+}
+
+// setPrefix changes the topic prefix of the node and its properties
+func (n *Node) setPrefix(prefix, version string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.prefix = path.Join(prefix, n.id)
+	for _, prop := range n.properties {
+		prop.setPrefix(n.prefix)
+	}
+	if n.array {
+		n.setInstancesPrefix(prefix, version)
+	}
 }
 
 // Device returns the device the node is attached to.
@@ -52,12 +127,65 @@ func (n *Node) Device() *Device {
 // Property returns the property from the name.
 // it returns nil if the property does not exist
 func (n *Node) Property(id string) *Property {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
 	return n.properties[id]
 }
 
-func (n *Node) getAttributes() []TopicValuePair {
+// ID returns the ID of the node: the ID of an element of an array changes with the Homie version
+func (n *Node) ID() string {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	return n.id
+}
+
+// Name returns the name of the node, or a default name for an element of a node array
+func (n *Node) Name() string {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	return n.getName()
+}
+
+// Type returns the type of the node
+func (n *Node) Type() string {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	return n.nodeType
+}
+
+// Properties returns the properties of the node, sorted by ID
+func (n *Node) Properties() []*Property {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	properties := make([]*Property, 0, len(n.properties))
+	for _, prop := range n.properties {
+		properties = append(properties, prop)
+	}
+	sort.Slice(properties, func(i, j int) bool {
+		return properties[i].id < properties[j].id
+	})
+	return properties
+}
+
+func (n *Node) getAttributes(version string) []TopicValuePair {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	if n.array && version != "3" {
+		n.syncInstances()
+		attributes := make([]TopicValuePair, 0, 6*len(n.properties)*len(n.instances))
+		for _, instance := range n.instances {
+			attributes = append(attributes, instance.getAttributes(version)...)
+		}
+		return attributes
+	}
 	attributes := make([]TopicValuePair, 0, 6*len(n.properties))
-	attributes = append(attributes, TopicValuePair{path.Join(n.prefix, attributeName), n.name})
+	attributes = append(attributes, TopicValuePair{path.Join(n.prefix, attributeName), n.getName()})
 	attributes = append(attributes, TopicValuePair{path.Join(n.prefix, attributeType), n.nodeType})
 
 	properties := ""
@@ -81,21 +209,51 @@ func (n *Node) getAttributes() []TopicValuePair {
 	for _, prop := range n.properties {
 		attributes = append(attributes, prop.getAttributes()...)
 	}
+	if n.array {
+		attributes = append(attributes, n.getArrayAttributes()...)
+	}
 	return attributes
 }
 
-func (n *Node) getValues() []TopicValuePair {
+func (n *Node) getValues(version string) []TopicValuePair {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	if n.array {
+		n.syncInstances()
+		attributes := make([]TopicValuePair, 0, len(n.properties)*len(n.instances))
+		for _, instance := range n.instances {
+			attributes = append(attributes, instance.getValues(version)...)
+		}
+		return attributes
+	}
 	attributes := make([]TopicValuePair, 0, len(n.properties))
 	if len(n.properties) == 0 {
 		return attributes
 	}
 	for _, prop := range n.properties {
 		attributes = append(attributes, prop.GetValue())
+		if target, ok := prop.getTarget(version); ok {
+			attributes = append(attributes, target)
+		}
 	}
 	return attributes
 }
 
 func (n *Node) getSetterProperties() map[string]*Property {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	if n.array {
+		n.syncInstances()
+		properties := make(map[string]*Property, len(n.properties)*len(n.instances))
+		for _, instance := range n.instances {
+			for topic, prop := range instance.getSetterProperties() {
+				properties[topic] = prop
+			}
+		}
+		return properties
+	}
 	properties := make(map[string]*Property, len(n.properties))
 	for _, prop := range n.properties {
 		topic := prop.getSetterTopic()
@@ -8,8 +8,8 @@ import (
 
 func TestGetEmptyNodeAttributes(t *testing.T) {
 	node := newNode(nil, "test", "nodeID", "nodeName", "nodeType")
-	attributes := node.getAttributes()
-	values := node.getValues()
+	attributes := node.getAttributes("4")
+	values := node.getValues("4")
 	assert.ElementsMatch(t, attributes, []TopicValuePair{
 		{"test/nodeID/$name", "nodeName"},
 		{"test/nodeID/$type", "nodeType"},
@@ -23,8 +23,8 @@ func TestGetNodeAttributes(t *testing.T) {
 	node.AddProperty("prop1", "prop1", TypeInteger).Set(10)
 	node.AddProperty("prop2", "prop2", TypeInteger).Set(20)
 
-	attributes := node.getAttributes()
-	values := node.getValues()
+	attributes := node.getAttributes("4")
+	values := node.getValues("4")
 	assert.ElementsMatch(t, attributes, []TopicValuePair{
 		{"test/nodeID/$name", "nodeName"},
 		{"test/nodeID/$type", "nodeType"},
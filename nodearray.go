@@ -0,0 +1,173 @@
+package homie
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+)
+
+// AddNodeArray creates and add an array of identical nodes to the device, with indexes from "from" to "to" (included).
+//
+// With Version3, the array is published as defined in the Homie 3 specification: the node ID is listed as <ID>[] in $nodes
+// and the elements are published under <ID>_<index>. As arrays were removed from the specification in Homie 4,
+// Version4 and Version5 publish each element of the array as a standalone node <ID>-<index>.
+//
+// Properties must be added to the array node: they are defined for every element of the array.
+// Use Index to access an element and set its property values: setting a value on a property of the array node
+// returns ErrArrayProperty. A change made on the property of an element (like a callback or a unit) is kept
+// until the property of the array node is changed again.
+//
+// It will panic if ID cannot be used in a topic, or if the range is invalid.
+//
+// see documentation: https://homieiot.github.io/specification/spec-core-v3_0_1/#arrays
+func (d *Device) AddNodeArray(id, name, nodeType string, from, to int) *Node {
+	if from < 0 || to < from {
+		panic(fmt.Sprintf("invalid node array range: %d-%d", from, to))
+	}
+	node := d.addNodeArray(id, name, nodeType, from, to)
+	d.structureChanged()
+	return node
+}
+
+func (d *Device) addNodeArray(id, name, nodeType string, from, to int) *Node {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	node := newNode(d, d.prefix, id, name, nodeType)
+	node.array = true
+	node.from = from
+	node.instances = make([]*Node, to-from+1)
+	for i := range node.instances {
+		node.instances[i] = &Node{
+			device:     d,
+			parent:     node,
+			index:      from + i,
+			nodeType:   nodeType,
+			properties: make(map[string]*Property, 1),
+		}
+	}
+	node.setPrefix(d.prefix, d.majorVersion())
+	d.nodes[id] = node
+	return node
+}
+
+// Index returns the element of the node array at this index.
+// It returns nil if the node is not an array or if the index is out of range.
+func (n *Node) Index(index int) *Node {
+	if !n.array || index < n.from || index >= n.from+len(n.instances) {
+		return nil
+	}
+	n.mutex.RLock()
+	n.syncInstances()
+	n.mutex.RUnlock()
+	return n.instances[index-n.from]
+}
+
+// SetIndexName defines the name of the element of the node array at this index
+func (n *Node) SetIndexName(index int, name string) *Node {
+	if instance := n.Index(index); instance != nil {
+		instance.mutex.Lock()
+		instance.name = name
+		instance.mutex.Unlock()
+	}
+	return n
+}
+
+// syncInstances copies the definition of the array properties into each element of the array,
+// when the element property is created or when the definition of the array property changed.
+// The changes made on the property of an element are kept until the array property is changed again.
+// The mutex of the array node must be held by the caller.
+func (n *Node) syncInstances() {
+	for _, instance := range n.instances {
+		instance.mutex.Lock()
+		for id, definition := range n.properties {
+			prop := instance.properties[id]
+			created := prop == nil
+			if created {
+				prop = newProperty(instance, instance.prefix, id, "", "")
+				instance.properties[id] = prop
+			}
+			prop.copyDefinition(definition, created)
+		}
+		instance.mutex.Unlock()
+	}
+}
+
+// checkArray returns an error for a property of a node array: it only holds the definition,
+// the values are set on the elements of the array (see Index).
+func (p *Property) checkArray() error {
+	if p.node != nil && p.node.array {
+		return fmt.Errorf("%w: use Index to set the value of '%s'", ErrArrayProperty, p.id)
+	}
+	return nil
+}
+
+// setInstancesPrefix calculates the ID and topic prefix of the elements of the array, depending on the Homie version.
+// The mutex of the array node must be held by the caller.
+func (n *Node) setInstancesPrefix(prefix, version string) {
+	separator := "-"
+	if version == "3" {
+		separator = "_"
+	}
+	for _, instance := range n.instances {
+		instance.mutex.Lock()
+		instance.id = n.id + separator + strconv.Itoa(instance.index)
+		instance.mutex.Unlock()
+		instance.setPrefix(prefix, version)
+	}
+}
+
+// getArrayAttributes returns the attributes specific to a Homie 3 array.
+// The mutex of the array node must be held by the caller.
+func (n *Node) getArrayAttributes() []TopicValuePair {
+	attributes := make([]TopicValuePair, 0, len(n.instances)+1)
+	to := n.from + len(n.instances) - 1
+	attributes = append(attributes, TopicValuePair{path.Join(n.prefix, attributeArray), strconv.Itoa(n.from) + "-" + strconv.Itoa(to)})
+	for _, instance := range n.instances {
+		instance.mutex.RLock()
+		if instance.name != "" {
+			attributes = append(attributes, TopicValuePair{path.Join(instance.prefix, attributeName), instance.name})
+		}
+		instance.mutex.RUnlock()
+	}
+	return attributes
+}
+
+// getName returns the name of the node, or a default name for an element of an array.
+// The mutex of the node must be held by the caller (the name of the array itself never changes).
+func (n *Node) getName() string {
+	if n.name == "" && n.parent != nil {
+		return n.parent.name + " " + strconv.Itoa(n.index)
+	}
+	return n.name
+}
+
+// majorVersion returns the major version of the Homie convention used by the device
+func (n *Node) majorVersion() string {
+	if n.device == nil {
+		return "4"
+	}
+	n.device.mutex.RLock()
+	defer n.device.mutex.RUnlock()
+
+	return n.device.majorVersion()
+}
+
+// expandedNodes returns the nodes as published in Homie 4 and 5: the arrays are replaced by their elements.
+// The mutex of the device must be held by the caller.
+func (d *Device) expandedNodes() map[string]*Node {
+	nodes := make(map[string]*Node, len(d.nodes))
+	for id, node := range d.nodes {
+		if !node.array {
+			nodes[id] = node
+			continue
+		}
+		node.mutex.RLock()
+		node.syncInstances()
+		node.mutex.RUnlock()
+		for _, instance := range node.instances {
+			nodes[instance.ID()] = instance
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,152 @@
+package homie
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeArrayVersion3(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName").SetVersion(Version3)
+	device.
+		AddNodeArray("lights", "Lights", "relay", 1, 2).
+		SetIndexName(1, "Kitchen").
+		AddProperty("power", "Power", TypeBoolean).Settable(true)
+	device.Node("lights").Index(1).Property("power").Set(true)
+	device.Node("lights").Index(2).Property("power").Set(false)
+
+	attributes := device.GetHomieAttributes()
+	assert.Contains(t, attributes, TopicValuePair{"homie/deviceID/$nodes", "lights[]"})
+	assert.Subset(t, attributes, []TopicValuePair{
+		{"homie/deviceID/lights/$name", "Lights"},
+		{"homie/deviceID/lights/$type", "relay"},
+		{"homie/deviceID/lights/$properties", "power"},
+		{"homie/deviceID/lights/$array", "1-2"},
+		{"homie/deviceID/lights_1/$name", "Kitchen"},
+		{"homie/deviceID/lights/power/$name", "Power"},
+		{"homie/deviceID/lights/power/$datatype", "boolean"},
+		{"homie/deviceID/lights/power/$settable", "true"},
+	})
+	assert.NotContains(t, attributes, TopicValuePair{"homie/deviceID/lights_2/$name", ""})
+	assert.ElementsMatch(t, device.GetValues(), []TopicValuePair{
+		{"homie/deviceID/lights_1/power", "true"},
+		{"homie/deviceID/lights_2/power", "false"},
+	})
+	setters := device.GetPropertySetters()
+	assert.Len(t, setters, 2)
+	assert.NotNil(t, setters["homie/deviceID/lights_1/power/set"])
+	assert.NotNil(t, setters["homie/deviceID/lights_2/power/set"])
+}
+
+func TestNodeArrayVersion4(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName")
+	device.
+		AddNodeArray("lights", "Lights", "relay", 1, 2).
+		SetIndexName(1, "Kitchen").
+		AddProperty("power", "Power", TypeBoolean).Settable(true)
+	device.Node("lights").Index(2).Property("power").Set(true)
+
+	assert.ElementsMatch(t, device.GetHomieAttributes(), []TopicValuePair{
+		{"homie/deviceID/$homie", "4.0.0"},
+		{"homie/deviceID/$name", "deviceName"},
+		{"homie/deviceID/$state", "init"},
+		{"homie/deviceID/$nodes", "lights-1,lights-2"},
+		{"homie/deviceID/$extensions", ""},
+		{"homie/deviceID/lights-1/$name", "Kitchen"},
+		{"homie/deviceID/lights-1/$type", "relay"},
+		{"homie/deviceID/lights-1/$properties", "power"},
+		{"homie/deviceID/lights-1/power/$name", "Power"},
+		{"homie/deviceID/lights-1/power/$datatype", "boolean"},
+		{"homie/deviceID/lights-1/power/$settable", "true"},
+		{"homie/deviceID/lights-2/$name", "Lights 2"},
+		{"homie/deviceID/lights-2/$type", "relay"},
+		{"homie/deviceID/lights-2/$properties", "power"},
+		{"homie/deviceID/lights-2/power/$name", "Power"},
+		{"homie/deviceID/lights-2/power/$datatype", "boolean"},
+		{"homie/deviceID/lights-2/power/$settable", "true"},
+	})
+	assert.ElementsMatch(t, device.GetValues(), []TopicValuePair{
+		{"homie/deviceID/lights-1/power", ""},
+		{"homie/deviceID/lights-2/power", "true"},
+	})
+}
+
+func TestNodeArrayVersion5(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName").SetVersion(Version5)
+	device.AddNodeArray("lights", "Lights", "relay", 1, 2).AddProperty("power", "Power", TypeBoolean)
+	assert.Contains(t, device.GetDescription(), `"lights-2":{"name":"Lights 2","type":"relay"`)
+}
+
+func TestNodeArrayCommand(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName").SetVersion(Version3)
+	device.AddNodeArray("lights", "Lights", "relay", 1, 2).AddProperty("power", "Power", TypeBoolean).Settable(true)
+	err := device.HandleMessage("homie/deviceID/lights_2/power/set", "true")
+	require.NoError(t, err)
+	assert.Equal(t, "true", device.Node("lights").Index(2).Property("power").GetValue().Value)
+	assert.Equal(t, "", device.Node("lights").Index(1).Property("power").GetValue().Value)
+}
+
+func TestNodeArrayIndexOutOfRange(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName")
+	device.AddNodeArray("lights", "Lights", "relay", 1, 2)
+	assert.Nil(t, device.Node("lights").Index(0))
+	assert.Nil(t, device.Node("lights").Index(3))
+	device.AddNode("node", "node", "node")
+	assert.Nil(t, device.Node("node").Index(0))
+}
+
+func TestInvalidNodeArrayRange(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName")
+	assert.Panics(t, func() {
+		device.AddNodeArray("lights", "Lights", "relay", 2, 1)
+	})
+}
+
+func TestNodeArrayElementDefinition(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName")
+	lights := device.AddNodeArray("lights", "Lights", "relay", 1, 2)
+	lights.AddProperty("power", "Power", TypeInteger).SetUnit("W")
+
+	commands := 0
+	lights.Index(1).Property("power").
+		SetUnit("kW").
+		OnCommand(func(p *Property, raw string) error {
+			commands++
+			return nil
+		}).
+		Settable(true)
+
+	attributes := device.GetHomieAttributes()
+	assert.Contains(t, attributes, TopicValuePair{"homie/deviceID/lights-1/power/$unit", "kW"})
+	assert.Contains(t, attributes, TopicValuePair{"homie/deviceID/lights-2/power/$unit", "W"})
+	require.NoError(t, device.HandleMessage("homie/deviceID/lights-1/power/set", "10"))
+	assert.Equal(t, 1, commands)
+
+	// a change on the array property is copied again into every element
+	lights.Property("power").SetUnit("mW")
+	attributes = device.GetHomieAttributes()
+	assert.Contains(t, attributes, TopicValuePair{"homie/deviceID/lights-1/power/$unit", "mW"})
+	assert.Contains(t, attributes, TopicValuePair{"homie/deviceID/lights-2/power/$unit", "mW"})
+}
+
+func TestNodeArrayPropertyValue(t *testing.T) {
+	transport := &mockTransport{}
+	device := NewDevice("deviceID", "deviceName").SetTransport(transport)
+	lights := device.AddNodeArray("lights", "Lights", "relay", 1, 2)
+	lights.AddProperty("power", "Power", TypeBoolean)
+
+	err := lights.Property("power").TrySet(true)
+	assert.True(t, errors.Is(err, ErrArrayProperty))
+	err = device.Batch(func(batch *Batch) {
+		batch.Set(lights.Property("power"), true)
+	})
+	assert.True(t, errors.Is(err, ErrArrayProperty))
+	err = lights.Update(map[string]interface{}{"power": true})
+	assert.True(t, errors.Is(err, ErrArrayProperty))
+	lights.Property("power").SetTarget(true)
+
+	assert.Empty(t, transport.messages)
+	assert.Equal(t, "", lights.Property("power").Value())
+}
@@ -0,0 +1,99 @@
+package homie
+
+import "time"
+
+type publishMode int
+
+const (
+	publishDefault publishMode = iota
+	publishAlways
+	publishOnChange
+)
+
+// PublishPolicy decides if a new property value is sent to the callbacks and the transport
+type PublishPolicy struct {
+	mode     publishMode
+	interval time.Duration
+}
+
+// Publish policies
+var (
+	// PublishAlways publishes every value, even when it didn't change. This is the default policy.
+	PublishAlways = PublishPolicy{mode: publishAlways}
+	// PublishOnChange only publishes a value different from the last one published
+	PublishOnChange = PublishPolicy{mode: publishOnChange}
+)
+
+// PublishOnChangeOrEvery publishes a value different from the last one published,
+// or the same value again when the last publication is older than the interval (heartbeat).
+func PublishOnChangeOrEvery(interval time.Duration) PublishPolicy {
+	return PublishPolicy{mode: publishOnChange, interval: interval}
+}
+
+// SetPublishPolicy defines the default publish policy of the properties of the device.
+// The default is PublishAlways.
+func (d *Device) SetPublishPolicy(policy PublishPolicy) *Device {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.policy = policy
+	return d
+}
+
+// SetPublishPolicy defines the publish policy of the property, instead of the policy of the device
+func (p *Property) SetPublishPolicy(policy PublishPolicy) *Property {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.policy = policy
+	p.revision++
+	return p
+}
+
+// publishOptions decide when the values of a property are published
+type publishOptions struct {
+	policy    PublishPolicy
+	rateLimit time.Duration
+	clock     Clock
+}
+
+// publishOptions returns the options of the property, or the options of the device for those not defined on the property
+func (p *Property) publishOptions() publishOptions {
+	p.mutex.RLock()
+	options := publishOptions{policy: p.policy, rateLimit: p.rateLimit}
+	hasRateLimit := p.hasRateLimit
+	p.mutex.RUnlock()
+
+	if device := p.device(); device != nil {
+		device.mutex.RLock()
+		if options.policy.mode == publishDefault {
+			options.policy = device.policy
+		}
+		if !hasRateLimit {
+			options.rateLimit = device.rateLimit
+		}
+		options.clock = device.clock
+		device.mutex.RUnlock()
+	}
+	if options.policy.mode == publishDefault {
+		options.policy = PublishAlways
+	}
+	if options.clock == nil {
+		options.clock = systemClock{}
+	}
+	return options
+}
+
+// shouldPublish applies the policy and the dead band to the new payload.
+// The mutex must be held by the caller.
+func (p *Property) shouldPublish(payload string, policy PublishPolicy, current time.Time) bool {
+	if p.publishedAt.IsZero() {
+		return true
+	}
+	unchanged := p.insideDeadband(payload) || (policy.mode == publishOnChange && payload == p.publishedValue)
+	if !unchanged {
+		return true
+	}
+	// heartbeat
+	return policy.mode == publishOnChange && policy.interval > 0 && current.Sub(p.publishedAt) >= policy.interval
+}
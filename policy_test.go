@@ -0,0 +1,99 @@
+package homie
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishAlwaysByDefault(t *testing.T) {
+	values := make([]string, 0)
+	prop := NewDevice("deviceID", "deviceName").
+		OnSet(collectValues(&values)).
+		AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeInteger)
+	prop.Set(1).Set(1).Set(2)
+	assert.Equal(t, []string{"1", "1", "2"}, values)
+}
+
+func TestPublishOnChange(t *testing.T) {
+	values := make([]string, 0)
+	prop := NewDevice("deviceID", "deviceName").
+		OnSet(collectValues(&values)).
+		AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeInteger).SetPublishPolicy(PublishOnChange)
+
+	prop.Set(1).Set(1).Set(2).Set(2).Set(1)
+	assert.Equal(t, []string{"1", "2", "1"}, values)
+	assert.Equal(t, "1", prop.GetValue().Value)
+}
+
+func TestPublishOnChangeFromDevice(t *testing.T) {
+	values := make([]string, 0)
+	prop := NewDevice("deviceID", "deviceName").
+		SetPublishPolicy(PublishOnChange).
+		OnSet(collectValues(&values)).
+		AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeInteger)
+
+	prop.Set(1).Set(1)
+	assert.Equal(t, []string{"1"}, values)
+
+	// the policy of the property wins
+	prop.SetPublishPolicy(PublishAlways).Set(1)
+	assert.Equal(t, []string{"1", "1"}, values)
+}
+
+func TestPublishOnChangeOrEvery(t *testing.T) {
+	clock := newFakeClock()
+	values := make([]string, 0)
+	prop := NewDevice("deviceID", "deviceName").
+		SetClock(clock).
+		OnSet(collectValues(&values)).
+		AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeInteger).SetPublishPolicy(PublishOnChangeOrEvery(time.Minute))
+
+	prop.Set(1)
+	clock.Advance(30 * time.Second)
+	prop.Set(1)
+	assert.Equal(t, []string{"1"}, values)
+
+	// heartbeat
+	clock.Advance(30 * time.Second)
+	prop.Set(1)
+	assert.Equal(t, []string{"1", "1"}, values)
+
+	clock.Advance(time.Second)
+	prop.Set(1).Set(2)
+	assert.Equal(t, []string{"1", "1", "2"}, values)
+}
+
+func TestPublishPolicyOnTransport(t *testing.T) {
+	transport := newMockTransport()
+	device := NewDevice("deviceID", "deviceName").SetTransport(transport).SetPublishPolicy(PublishOnChange)
+	prop := device.AddNode("node1", "node1 name", "test1").AddProperty("prop1", "prop1 name", TypeInteger).Settable(true)
+	prop.Set(10)
+	assert.NoError(t, device.Publish())
+	transport.messages = transport.messages[:0]
+
+	prop.Set(10)
+	assert.Empty(t, transport.messages)
+
+	// a command is always confirmed
+	transport.send("homie/deviceID/node1/prop1/set", "10")
+	assert.Equal(t, []mockMessage{{"homie/deviceID/node1/prop1", QoS, true, "10"}}, transport.messages)
+}
+
+func TestPublishPolicyOnNodeArray(t *testing.T) {
+	values := make([]string, 0)
+	device := NewDevice("deviceID", "deviceName").OnSet(func(topic, value string, dataType PropertyType) {
+		values = append(values, topic+"="+value)
+	})
+	array := device.AddNodeArray("relays", "Relays", "relay", 1, 2)
+	array.AddProperty("power", "Power", TypeBoolean).SetPublishPolicy(PublishOnChange)
+
+	array.Index(1).Property("power").Set(true).Set(true)
+	array.Index(2).Property("power").Set(true)
+	assert.Equal(t, []string{"homie/deviceID/relays-1/power=true", "homie/deviceID/relays-2/power=true"}, values)
+}
@@ -0,0 +1,129 @@
+package homie
+
+import "strings"
+
+// RoundingMode defines how a float value is rounded to the precision of the property
+type RoundingMode int
+
+// RoundingMode
+const (
+	// RoundHalfAwayFromZero rounds to the nearest value, and halfway values away from zero (like math.Round). This is the default.
+	RoundHalfAwayFromZero RoundingMode = iota
+	// RoundHalfEven rounds to the nearest value, and halfway values to the nearest even digit (like math.RoundToEven)
+	RoundHalfEven
+	// RoundTowardZero drops the extra decimals (like math.Trunc)
+	RoundTowardZero
+	// RoundFloor rounds toward negative infinity (like math.Floor)
+	RoundFloor
+	// RoundCeil rounds toward positive infinity (like math.Ceil)
+	RoundCeil
+)
+
+// SetPrecision publishes the values of a float property with a fixed number of decimals.
+// A negative precision publishes all the significant decimals (this is the default).
+// It is ignored on the other data types.
+//
+// The values are rounded according to the rounding mode of the property (see SetRounding),
+// before being validated against the format.
+func (p *Property) SetPrecision(decimals int) *Property {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.precision = decimals
+	p.revision++
+	return p
+}
+
+// SetRounding defines how the values of a float property are rounded to its precision.
+// The default is RoundHalfAwayFromZero.
+func (p *Property) SetRounding(mode RoundingMode) *Property {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.rounding = mode
+	p.revision++
+	return p
+}
+
+// formatValue converts the value into a payload for the property, rounded to its precision.
+// The mutex must be held by the caller.
+func (p *Property) formatValue(value interface{}) (string, error) {
+	payload, err := canonicalValue(p.dataType, value)
+	if err != nil {
+		return "", err
+	}
+	if p.dataType == TypeFloat && p.precision >= 0 {
+		payload = roundDecimal(payload, p.precision, p.rounding)
+	}
+	err = validateValue(p.dataType, p.format, payload)
+	if err != nil {
+		return "", err
+	}
+	return payload, nil
+}
+
+// roundDecimal rounds a number in decimal notation (without exponent) to a fixed number of decimals.
+// The rounding is done on the decimal digits, so 1.005 is rounded to 1.01 as expected (and not 1.00 as math.Round would do).
+func roundDecimal(number string, decimals int, mode RoundingMode) string {
+	negative := strings.HasPrefix(number, "-")
+	number = strings.TrimPrefix(number, "-")
+	integer, fraction := number, ""
+	if dot := strings.IndexByte(number, '.'); dot >= 0 {
+		integer, fraction = number[:dot], number[dot+1:]
+	}
+	if len(fraction) < decimals {
+		fraction += strings.Repeat("0", decimals-len(fraction))
+	}
+	digits := []byte(integer + fraction[:decimals])
+	rest := fraction[decimals:]
+
+	if roundUp(digits, rest, negative, mode) {
+		digits = increment(digits)
+	}
+
+	integer, fraction = string(digits[:len(digits)-decimals]), string(digits[len(digits)-decimals:])
+	result := integer
+	if decimals > 0 {
+		result += "." + fraction
+	}
+	if negative && strings.Trim(result, "0.") != "" {
+		result = "-" + result
+	}
+	return result
+}
+
+// roundUp returns true when the magnitude of the kept digits must be incremented
+func roundUp(digits []byte, rest string, negative bool, mode RoundingMode) bool {
+	discarded := strings.Trim(rest, "0") != ""
+	if !discarded {
+		return false
+	}
+	switch mode {
+	case RoundTowardZero:
+		return false
+	case RoundFloor:
+		return negative
+	case RoundCeil:
+		return !negative
+	case RoundHalfEven:
+		if rest[0] != '5' || strings.Trim(rest[1:], "0") != "" {
+			return rest[0] >= '5'
+		}
+		// exactly halfway
+		return len(digits) > 0 && (digits[len(digits)-1]-'0')%2 == 1
+	default:
+		return rest[0] >= '5'
+	}
+}
+
+// increment adds one to the number represented by the decimal digits
+func increment(digits []byte) []byte {
+	for i := len(digits) - 1; i >= 0; i-- {
+		if digits[i] < '9' {
+			digits[i]++
+			return digits
+		}
+		digits[i] = '0'
+	}
+	return append([]byte{'1'}, digits...)
+}
@@ -0,0 +1,74 @@
+package homie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundDecimal(t *testing.T) {
+	testData := []struct {
+		number   string
+		decimals int
+		mode     RoundingMode
+		expected string
+	}{
+		{"28.123456789", 2, RoundHalfAwayFromZero, "28.12"},
+		{"28", 2, RoundHalfAwayFromZero, "28.00"},
+		{"28.5", 0, RoundHalfAwayFromZero, "29"},
+		{"1.005", 2, RoundHalfAwayFromZero, "1.01"},
+		{"9.995", 2, RoundHalfAwayFromZero, "10.00"},
+		{"99.5", 0, RoundHalfAwayFromZero, "100"},
+		{"-1.005", 2, RoundHalfAwayFromZero, "-1.01"},
+		{"-0.001", 2, RoundHalfAwayFromZero, "0.00"},
+		{"0.125", 2, RoundHalfEven, "0.12"},
+		{"0.135", 2, RoundHalfEven, "0.14"},
+		{"0.1251", 2, RoundHalfEven, "0.13"},
+		{"2.5", 0, RoundHalfEven, "2"},
+		{"1.999", 2, RoundTowardZero, "1.99"},
+		{"-1.999", 2, RoundTowardZero, "-1.99"},
+		{"1.001", 2, RoundFloor, "1.00"},
+		{"-1.001", 2, RoundFloor, "-1.01"},
+		{"1.001", 2, RoundCeil, "1.01"},
+		{"-1.001", 2, RoundCeil, "-1.00"},
+		{"1000000", 1, RoundHalfAwayFromZero, "1000000.0"},
+	}
+	for _, testItem := range testData {
+		t.Run(testItem.number, func(t *testing.T) {
+			assert.Equal(t, testItem.expected, roundDecimal(testItem.number, testItem.decimals, testItem.mode))
+		})
+	}
+}
+
+func TestSetPrecision(t *testing.T) {
+	prop := newProperty(nil, "test", "id", "name", TypeFloat).SetPrecision(2)
+	assert.Equal(t, "28.12", prop.Set(28.123456789).Value())
+	assert.Equal(t, "1000000.00", prop.Set(1e6).Value())
+	assert.Equal(t, "0.00", prop.Set(1e-20).Value())
+	assert.Equal(t, "28.00", prop.Set(28).Value())
+
+	prop.SetRounding(RoundTowardZero)
+	assert.Equal(t, "28.99", prop.Set(28.999).Value())
+
+	prop.SetPrecision(-1)
+	assert.Equal(t, "28.999", prop.Set(28.999).Value())
+}
+
+func TestPrecisionBeforeValidation(t *testing.T) {
+	prop := newProperty(nil, "test", "id", "name", TypeFloat).SetRange(0, 100).SetPrecision(1)
+	assert.NoError(t, prop.TrySet(100.04))
+	assert.Equal(t, "100.0", prop.Value())
+	assert.Error(t, prop.TrySet(100.05))
+}
+
+func TestPrecisionIgnoredOnIntegers(t *testing.T) {
+	prop := newProperty(nil, "test", "id", "name", TypeInteger).SetPrecision(2)
+	assert.Equal(t, "28", prop.Set(28).Value())
+}
+
+func TestPrecisionOnNodeArray(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName")
+	array := device.AddNodeArray("meters", "Meters", "meter", 1, 2)
+	array.AddProperty("power", "Power", TypeFloat).SetPrecision(1)
+	assert.Equal(t, "12.3", array.Index(2).Property("power").Set(12.34).Value())
+}
@@ -2,7 +2,10 @@ package homie
 
 import (
 	"fmt"
+	"math"
 	"path"
+	"sync"
+	"time"
 )
 
 // PropertyType is the type of the property
@@ -10,27 +13,64 @@ type PropertyType string
 
 // PropertyType
 const (
-	TypeInteger PropertyType = "integer"
-	TypeFloat   PropertyType = "float"
-	TypeBoolean PropertyType = "boolean"
-	TypeString  PropertyType = "string"
-	TypeEnum    PropertyType = "enum"
-	TypeColor   PropertyType = "color"
+	TypeInteger  PropertyType = "integer"
+	TypeFloat    PropertyType = "float"
+	TypeBoolean  PropertyType = "boolean"
+	TypeString   PropertyType = "string"
+	TypeEnum     PropertyType = "enum"
+	TypeColor    PropertyType = "color"
+	TypeDatetime PropertyType = "datetime"
+	TypeDuration PropertyType = "duration"
 )
 
-// Property definition
+// Property definition.
+//
+// A property is safe for concurrent use: the value of different properties can be set from different goroutines.
+// When the same property is set concurrently, the values can be published in a different order than they were stored.
 type Property struct {
-	node     *Node
-	prefix   string
-	id       string
+	mutex  sync.RWMutex
+	node   *Node
+	prefix string
+	id     string
+	value  string
+	target string
+	propertyDefinition
+
+	// revision of the definition: the definition of a node array property is only copied
+	// into the elements of the array when its revision changed since the last copy (synced)
+	revision int
+	synced   int
+
+	// last value sent, for the publish policy
+	publishedValue string
+	publishedAt    time.Time
+
+	// value waiting for the end of the rate limit interval
+	pending    string
+	hasPending bool
+	timer      Timer
+}
+
+// propertyDefinition contains the fields copied to the elements of a node array
+type propertyDefinition struct {
 	name     string
 	dataType PropertyType
 	format   string
 	unit     string
 	settable bool
 	retained bool
-	value    string
 	setter   Setter
+
+	commandHandler CommandHandler
+	echo           bool
+	policy         PublishPolicy
+
+	deadband        float64
+	deadbandPercent bool
+	precision       int
+	rounding        RoundingMode
+	rateLimit       time.Duration
+	hasRateLimit    bool
 }
 
 func newProperty(node *Node, prefix, id, name string, dataType PropertyType) *Property {
@@ -38,12 +78,16 @@ func newProperty(node *Node, prefix, id, name string, dataType PropertyType) *Pr
 		panic(fmt.Sprintf("invalid property ID: '%s'", id))
 	}
 	return &Property{
-		node:     node,
-		prefix:   path.Join(prefix, id),
-		id:       id,
-		name:     name,
-		dataType: dataType,
-		retained: true,
+		node:   node,
+		prefix: path.Join(prefix, id),
+		id:     id,
+		propertyDefinition: propertyDefinition{
+			name:      name,
+			dataType:  dataType,
+			retained:  true,
+			echo:      true,
+			precision: -1,
+		},
 	}
 }
 
@@ -53,51 +97,237 @@ func (p *Property) Node() *Node {
 	return p.node
 }
 
+// ID returns the ID of the property
+func (p *Property) ID() string {
+	return p.id
+}
+
+// Name returns the name of the property
+func (p *Property) Name() string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.name
+}
+
+// Unit returns the unit of the property, or "" if not defined
+func (p *Property) Unit() string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.unit
+}
+
+// IsSettable returns true if the property accepts set commands
+func (p *Property) IsSettable() bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.settable
+}
+
+// IsRetained returns true if the values of the property are retained by the broker
+func (p *Property) IsRetained() bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.retained
+}
+
 // DataType returns the current type of the property
 func (p *Property) DataType() PropertyType {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
 	return p.dataType
 }
 
-// Set a new property value
+// Set a new property value.
+//
+// The value is converted to the payload format of the property data type.
+// A value which is not valid for the data type or the format is ignored: use TrySet if you need the error.
 func (p *Property) Set(value interface{}) *Property {
-	p.value = fmt.Sprintf("%v", value)
-	if p.setter != nil {
-		p.setter(p.prefix, p.value, p.dataType)
-	} else if p.node != nil && p.node.device != nil && p.node.device.setter != nil { // protection for unit tests creating lose properties
-		p.node.device.setter(p.prefix, p.value, p.dataType)
-	}
+	_ = p.TrySet(value)
 	return p
 }
 
+// TrySet validates and sets a new property value.
+//
+// The value is converted to the payload format of the property data type:
+// integers in base 10, floats without exponent (rounded to the precision of the property), booleans as true or false.
+// Enum values must be part of the format list, and colors must match the rgb or hsv format.
+//
+// An error is returned if the value is invalid (nothing is published), or if the transport failed to publish it.
+//
+// A valid value is always stored, but it is only published according to the publish policy (see SetPublishPolicy)
+// and the dead band of the property (see SetDeadband). When the property is rate limited (see SetRateLimit),
+// the value may be published later, after the end of the current interval.
+func (p *Property) TrySet(value interface{}) error {
+	return p.set(value, false)
+}
+
+// set stores the new value, and publishes it if the publish policy and the dead band allow it (or if forced).
+// A rate limited value is published at the end of the current interval instead.
+func (p *Property) set(value interface{}, force bool) error {
+	if err := p.checkArray(); err != nil {
+		return err
+	}
+	options := p.publishOptions()
+
+	p.mutex.Lock()
+	payload, err := p.formatValue(value)
+	if err != nil {
+		p.mutex.Unlock()
+		return err
+	}
+	p.value = payload
+	topic := p.prefix
+	current := options.clock.Now()
+	publish := force || p.shouldPublish(payload, options.policy, current)
+	if publish && !force && p.rateLimited(options.rateLimit, current) {
+		p.coalesce(payload, options.rateLimit, current, options.clock)
+		p.mutex.Unlock()
+		return nil
+	}
+	// a value waiting for the end of the interval is now obsolete
+	p.hasPending = false
+	if publish {
+		p.publishedValue = payload
+		p.publishedAt = current
+	}
+	p.mutex.Unlock()
+
+	if !publish {
+		return nil
+	}
+	return p.send(topic, payload)
+}
+
 // Settable tells the property if it can be set via a Homie set command.
 // for more information, https://homieiot.github.io/specification/#property-command-topic
 func (p *Property) Settable(settable bool) *Property {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
 	p.settable = settable
+	p.revision++
 	return p
 }
 
 // SetUnit defines a unit on the property
 func (p *Property) SetUnit(unit string) *Property {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
 	p.unit = unit
+	p.revision++
 	return p
 }
 
 // SetFormat defines a property format.
 // for more information on property format, see https://homieiot.github.io/specification/#properties
 func (p *Property) SetFormat(format string) *Property {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.format = format
+	p.revision++
+	return p
+}
+
+// SetRange defines the format of an integer or float property accepting values from min to max.
+// The bounds of an integer property are rounded inside the range: SetRange(0.5, 10.5) accepts values from 1 to 10.
+//
+// The format is left unchanged on other data types, or if the range is empty (like SetRange(0.5, 0.7) on an integer).
+func (p *Property) SetRange(min, max float64) *Property {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.dataType != TypeInteger && p.dataType != TypeFloat {
+		return p
+	}
+	if p.dataType == TypeInteger {
+		// adding zero turns a negative zero into zero
+		min, max = math.Ceil(min)+0, math.Floor(max)+0
+	}
+	format := Format{DataType: p.dataType, HasMin: true, Min: min, HasMax: true, Max: max}.String()
+	if _, err := ParseFormat(p.dataType, format); err != nil {
+		return p
+	}
 	p.format = format
+	p.revision++
 	return p
 }
 
+// SetEnumValues defines the format of an enum property with the list of accepted values
+func (p *Property) SetEnumValues(values ...string) *Property {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.format = Format{DataType: TypeEnum, Enum: values}.String()
+	p.revision++
+	return p
+}
+
+// SetColorFormat defines the format of a color property
+func (p *Property) SetColorFormat(color ColorFormat) *Property {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.format = string(color)
+	p.revision++
+	return p
+}
+
+// Format returns the parsed format of the property
+func (p *Property) Format() (Format, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return ParseFormat(p.dataType, p.format)
+}
+
 // SetRetained changes the retained flag as described:
 // https://homieiot.github.io/specification/#property-attributes
 func (p *Property) SetRetained(retained bool) *Property {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
 	p.retained = retained
+	p.revision++
+	return p
+}
+
+// SetTarget sets the value the property is moving towards (Homie 5 only), for example
+// the position of a motor moving a blind. The target is validated like a value:
+// an invalid target is ignored.
+//
+// see documentation: https://homieiot.github.io/specification/#property-target-attribute
+func (p *Property) SetTarget(target interface{}) *Property {
+	if p.checkArray() != nil {
+		return p
+	}
+	version := p.majorVersion()
+	p.mutex.Lock()
+	payload, err := p.formatValue(target)
+	if err != nil {
+		p.mutex.Unlock()
+		return p
+	}
+	p.target = payload
+	p.mutex.Unlock()
+
+	if target, ok := p.getTarget(version); ok {
+		_ = p.send(target.Topic, target.Value)
+	}
 	return p
 }
 
 // GetValue returns the Topic/Value pair of the property
 func (p *Property) GetValue() TopicValuePair {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
 	return TopicValuePair{
 		p.prefix,
 		p.value,
@@ -108,11 +338,123 @@ func (p *Property) GetValue() TopicValuePair {
 // if there's already a OnSet callback defined on the device, this callback will be used instead.
 // You pass nil to the method to remove the callback
 func (p *Property) OnSet(setter Setter) *Property {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
 	p.setter = setter
+	p.revision++
 	return p
 }
 
+// OnCommand defines a callback for when a set command is received for this property.
+// The handler can reject the command by returning an error.
+func (p *Property) OnCommand(handler CommandHandler) *Property {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.commandHandler = handler
+	p.revision++
+	return p
+}
+
+// EchoCommand defines if the value of an accepted set command is sent back to the property topic.
+// The default is true. Disable it if you prefer to Set the value yourself from the command handler.
+func (p *Property) EchoCommand(echo bool) *Property {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.echo = echo
+	p.revision++
+	return p
+}
+
+// copyDefinition copies the definition of another property (but not its value), if it changed since the last copy.
+// A new property always gets a copy.
+func (p *Property) copyDefinition(from *Property, created bool) {
+	from.mutex.RLock()
+	definition, revision := from.propertyDefinition, from.revision
+	from.mutex.RUnlock()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !created && p.synced == revision {
+		return
+	}
+	p.propertyDefinition = definition
+	p.synced = revision
+}
+
+// definition returns a copy of the definition of the property
+func (p *Property) definition() propertyDefinition {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.propertyDefinition
+}
+
+// setPrefix changes the topic prefix of the property
+func (p *Property) setPrefix(prefix string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.prefix = path.Join(prefix, p.id)
+}
+
+// device returns the device the property is attached to, or nil for a lose property
+func (p *Property) device() *Device {
+	if p.node == nil {
+		return nil
+	}
+	return p.node.device
+}
+
+// majorVersion returns the major version of the Homie convention used by the device, or "" for a lose property
+func (p *Property) majorVersion() string {
+	if p.node == nil {
+		return ""
+	}
+	return p.node.majorVersion()
+}
+
+// send a value of the property to the callback and the transport.
+// The callbacks are called without holding any lock.
+func (p *Property) send(topic, value string) error {
+	definition := p.definition()
+	setter := definition.setter
+	var transport Transport
+	if device := p.device(); device != nil {
+		device.mutex.RLock()
+		if setter == nil {
+			setter = device.setter
+		}
+		transport = device.transport
+		device.mutex.RUnlock()
+	}
+	if setter != nil {
+		setter(topic, value, definition.dataType)
+	}
+	if transport != nil {
+		return transport.Publish(topic, QoS, definition.retained, value)
+	}
+	return nil
+}
+
+// getTarget returns the Topic/Value pair of the target, only if a target was set on a Homie 5 device
+func (p *Property) getTarget(version string) (TopicValuePair, bool) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if p.target == "" || version != "5" {
+		return TopicValuePair{}, false
+	}
+	return TopicValuePair{path.Join(p.prefix, attributeTarget), p.target}, true
+}
+
 func (p *Property) getSetterTopic() string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
 	if !p.settable {
 		return ""
 	}
@@ -120,6 +462,9 @@ func (p *Property) getSetterTopic() string {
 }
 
 func (p *Property) getAttributes() []TopicValuePair {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
 	attributes := make([]TopicValuePair, 0, 6)
 	attributes = append(attributes, TopicValuePair{path.Join(p.prefix, attributeName), p.name})
 	attributes = append(attributes, TopicValuePair{path.Join(p.prefix, attributeDatatype), string(p.dataType)})
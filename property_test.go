@@ -46,6 +46,13 @@ func TestGetSetterTopic(t *testing.T) {
 	assert.Equal(t, "test/id/set", prop.getSetterTopic())
 }
 
+// collectValues returns a Setter appending the published values
+func collectValues(values *[]string) Setter {
+	return func(topic, value string, dataType PropertyType) {
+		*values = append(*values, value)
+	}
+}
+
 func TestDeviceCallback(t *testing.T) {
 	call := false
 	onSet := func(topic, value string, dataType PropertyType) {
@@ -74,3 +81,22 @@ func TestCallbackOverride(t *testing.T) {
 	property.Set(true)
 	assert.Equal(t, 1, call)
 }
+
+func TestTrySetInvalidValue(t *testing.T) {
+	call := false
+	onSet := func(topic, value string, dataType PropertyType) {
+		call = true
+	}
+	prop := newProperty(nil, "test", "id", "name", TypeBoolean).OnSet(onSet)
+	err := prop.TrySet("yes")
+	assert.Error(t, err)
+	assert.False(t, call)
+	assert.Equal(t, TopicValuePair{"test/id", ""}, prop.GetValue())
+}
+
+func TestSetInvalidValueIsIgnored(t *testing.T) {
+	prop := newProperty(nil, "test", "id", "name", TypeFloat)
+	prop.Set(10.5)
+	prop.Set(nil)
+	assert.Equal(t, TopicValuePair{"test/id", "10.5"}, prop.GetValue())
+}
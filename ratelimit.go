@@ -0,0 +1,95 @@
+package homie
+
+import "time"
+
+// SetRateLimit publishes at most one value per interval for each property of the device.
+// The values set during the interval are coalesced: only the latest one is published at the end of the interval.
+//
+// A rate limit of zero removes the limit.
+func (d *Device) SetRateLimit(interval time.Duration) *Device {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.rateLimit = interval
+	return d
+}
+
+// SetRateLimit publishes at most one value per interval, instead of the rate limit of the device.
+// The values set during the interval are coalesced: only the latest one is published at the end of the interval.
+//
+// A rate limit of zero removes the limit on this property, even if the device has one.
+// The value of a set command is always published immediately.
+func (p *Property) SetRateLimit(interval time.Duration) *Property {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.rateLimit = interval
+	p.hasRateLimit = true
+	p.revision++
+	return p
+}
+
+// rateLimited returns true if the value must wait for the end of the current interval.
+// The mutex must be held by the caller.
+func (p *Property) rateLimited(interval time.Duration, current time.Time) bool {
+	if interval <= 0 || p.publishedAt.IsZero() {
+		return false
+	}
+	return p.hasPending || current.Sub(p.publishedAt) < interval
+}
+
+// coalesce keeps the payload until the end of the current interval, replacing any value already waiting.
+// The mutex must be held by the caller.
+func (p *Property) coalesce(payload string, interval time.Duration, current time.Time, clock Clock) {
+	p.pending = payload
+	p.hasPending = true
+	if p.timer != nil {
+		return
+	}
+	p.timer = clock.AfterFunc(interval-current.Sub(p.publishedAt), func() {
+		p.flush(clock)
+	})
+}
+
+// flush publishes the value waiting for the end of the interval, if any
+func (p *Property) flush(clock Clock) {
+	p.mutex.Lock()
+	p.timer = nil
+	if !p.hasPending {
+		p.mutex.Unlock()
+		return
+	}
+	payload, topic := p.pending, p.prefix
+	p.hasPending = false
+	p.publishedValue = payload
+	p.publishedAt = clock.Now()
+	p.mutex.Unlock()
+
+	// nobody is waiting for the error
+	_ = p.send(topic, payload)
+}
+
+// cancelPending drops the value waiting for the end of the interval, when the property is removed
+func (p *Property) cancelPending() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	p.hasPending = false
+}
+
+// cancelPending drops the values waiting for the end of the interval in the node (and the elements of an array)
+func (n *Node) cancelPending() {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	for _, prop := range n.properties {
+		prop.cancelPending()
+	}
+	for _, instance := range n.instances {
+		instance.cancelPending()
+	}
+}
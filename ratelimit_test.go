@@ -0,0 +1,128 @@
+package homie
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitCoalesces(t *testing.T) {
+	clock := newFakeClock()
+	values := make([]string, 0)
+	prop := NewDevice("deviceID", "deviceName").
+		SetClock(clock).
+		OnSet(collectValues(&values)).
+		AddNode("meter", "Meter", "meter").
+		AddProperty("power", "Power", TypeFloat).SetRateLimit(time.Second)
+
+	// 50 Hz
+	for i := 1; i <= 100; i++ {
+		prop.Set(i)
+		clock.Advance(20 * time.Millisecond)
+	}
+	assert.Equal(t, []string{"1", "50", "100"}, values)
+	assert.Equal(t, "100", prop.Value())
+	assert.Equal(t, "100", prop.PublishedValue())
+
+	// nothing waiting anymore
+	clock.Advance(time.Minute)
+	assert.Len(t, values, 3)
+
+	// out of the window: published immediately
+	prop.Set(101)
+	assert.Equal(t, []string{"1", "50", "100", "101"}, values)
+}
+
+func TestRateLimitFromDevice(t *testing.T) {
+	clock := newFakeClock()
+	values := make([]string, 0)
+	node := NewDevice("deviceID", "deviceName").
+		SetClock(clock).
+		SetRateLimit(time.Second).
+		OnSet(collectValues(&values)).
+		AddNode("meter", "Meter", "meter")
+	power := node.AddProperty("power", "Power", TypeFloat)
+	energy := node.AddProperty("energy", "Energy", TypeFloat)
+
+	// each property has its own window
+	power.Set(1).Set(2).Set(3)
+	energy.Set(10).Set(20)
+	assert.Equal(t, []string{"1", "10"}, values)
+
+	clock.Advance(time.Second)
+	assert.ElementsMatch(t, []string{"1", "10", "3", "20"}, values)
+
+	// the property can opt out
+	values = values[:0]
+	energy.SetRateLimit(0).Set(30).Set(40)
+	assert.Equal(t, []string{"30", "40"}, values)
+}
+
+func TestRateLimitWithPublishOnChange(t *testing.T) {
+	clock := newFakeClock()
+	values := make([]string, 0)
+	prop := NewDevice("deviceID", "deviceName").
+		SetClock(clock).
+		SetRateLimit(time.Second).
+		SetPublishPolicy(PublishOnChange).
+		OnSet(collectValues(&values)).
+		AddNode("meter", "Meter", "meter").
+		AddProperty("power", "Power", TypeFloat)
+
+	// back to the published value before the end of the window: nothing to publish
+	prop.Set(1).Set(2).Set(1)
+	clock.Advance(time.Second)
+	assert.Equal(t, []string{"1"}, values)
+}
+
+func TestRateLimitDoesNotDelayCommands(t *testing.T) {
+	clock := newFakeClock()
+	transport := newMockTransport()
+	device := NewDevice("deviceID", "deviceName").SetClock(clock).SetTransport(transport).SetRateLimit(time.Second)
+	prop := device.AddNode("node1", "node1 name", "test1").AddProperty("prop1", "prop1 name", TypeInteger).Settable(true)
+	require.NoError(t, device.Publish())
+
+	prop.Set(1).Set(2)
+	transport.messages = transport.messages[:0]
+	transport.send("homie/deviceID/node1/prop1/set", "3")
+	assert.Equal(t, []mockMessage{{"homie/deviceID/node1/prop1", QoS, true, "3"}}, transport.messages)
+
+	// the value waiting was replaced by the command
+	clock.Advance(time.Second)
+	assert.Len(t, transport.messages, 1)
+}
+
+func TestRateLimitDroppedOnRemoval(t *testing.T) {
+	remove := map[string]func(device *Device) error{
+		"node": func(device *Device) error {
+			_, err := device.RemoveNode("meter")
+			return err
+		},
+		"property": func(device *Device) error {
+			_, err := device.Node("meter").RemoveProperty("power")
+			return err
+		},
+		"device": func(device *Device) error {
+			_, err := device.Remove()
+			return err
+		},
+	}
+	for name, remove := range remove {
+		t.Run(name, func(t *testing.T) {
+			clock := newFakeClock()
+			transport := newMockTransport()
+			device := NewDevice("deviceID", "deviceName").SetClock(clock).SetTransport(transport)
+			prop := device.AddNode("meter", "Meter", "meter").
+				AddProperty("power", "Power", TypeInteger).SetRateLimit(time.Second)
+
+			prop.Set(1).Set(2)
+			require.NoError(t, remove(device))
+			count := len(transport.messages)
+			clock.Advance(time.Minute)
+			assert.Len(t, transport.messages, count)
+			assert.NotContains(t, transport.pairs(), TopicValuePair{"homie/deviceID/meter/power", "2"})
+		})
+	}
+}
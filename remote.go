@@ -0,0 +1,136 @@
+package homie
+
+import (
+	"context"
+	"path"
+)
+
+// RemoteProperty sends commands to a property of a discovered device
+type RemoteProperty struct {
+	discovery  *Discovery
+	deviceID   string
+	nodeID     string
+	propertyID string
+}
+
+type valueWaiter struct {
+	topic string
+	value string
+	done  chan struct{}
+}
+
+// RemoteProperty returns a controller for the property of a device.
+// The device doesn't need to be discovered yet, but it needs to be before sending any command.
+func (d *Discovery) RemoteProperty(deviceID, nodeID, propertyID string) *RemoteProperty {
+	return &RemoteProperty{
+		discovery:  d,
+		deviceID:   deviceID,
+		nodeID:     nodeID,
+		propertyID: propertyID,
+	}
+}
+
+// Property returns the discovered definition of the property, or nil if it's not discovered yet
+func (r *RemoteProperty) Property() *Property {
+	device := r.discovery.Device(r.deviceID)
+	if device == nil {
+		return nil
+	}
+	return device.findProperty(path.Join(r.nodeID, r.propertyID))
+}
+
+// Set validates the value against the discovered $datatype and $format of the property,
+// then publishes it to the property setter topic through the transport of the discovery.
+//
+// see documentation: https://homieiot.github.io/specification/#property-command-topic
+func (r *RemoteProperty) Set(value interface{}) error {
+	_, err := r.send(value, false)
+	return err
+}
+
+// SetAndWait sends the value like Set, then waits for the device to publish the new value on the property topic.
+// It returns the context error if the device didn't confirm the value before the context is done.
+func (r *RemoteProperty) SetAndWait(ctx context.Context, value interface{}) error {
+	waiter, err := r.send(value, true)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-waiter.done:
+		return nil
+	case <-ctx.Done():
+		r.discovery.removeWaiter(waiter)
+		return ctx.Err()
+	}
+}
+
+// send the command, registering a waiter for the confirmation of the value when needed
+func (r *RemoteProperty) send(value interface{}, wait bool) (*valueWaiter, error) {
+	prop := r.Property()
+	if prop == nil {
+		return nil, ErrUnknownProperty
+	}
+	definition := prop.definition()
+	if !definition.settable {
+		return nil, ErrNotSettable
+	}
+	payload, err := formatValue(definition.dataType, definition.format, value)
+	if err != nil {
+		return nil, err
+	}
+	r.discovery.mutex.Lock()
+	transport := r.discovery.transport
+	r.discovery.mutex.Unlock()
+	if transport == nil {
+		return nil, ErrNoTransport
+	}
+	var waiter *valueWaiter
+	if wait {
+		waiter = r.discovery.addWaiter(prop.GetValue().Topic, payload)
+	}
+	err = transport.Publish(prop.getSetterTopic(), QoS, false, payload)
+	if err != nil {
+		if waiter != nil {
+			r.discovery.removeWaiter(waiter)
+		}
+		return nil, err
+	}
+	return waiter, nil
+}
+
+func (d *Discovery) addWaiter(topic, value string) *valueWaiter {
+	waiter := &valueWaiter{
+		topic: topic,
+		value: value,
+		done:  make(chan struct{}),
+	}
+	d.mutex.Lock()
+	d.waiters = append(d.waiters, waiter)
+	d.mutex.Unlock()
+	return waiter
+}
+
+func (d *Discovery) removeWaiter(waiter *valueWaiter) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for i, item := range d.waiters {
+		if item == waiter {
+			d.waiters = append(d.waiters[:i], d.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// notifyWaiters releases the waiters expecting this value. The mutex must be held by the caller.
+func (d *Discovery) notifyWaiters(topic, value string) {
+	waiters := d.waiters[:0]
+	for _, waiter := range d.waiters {
+		if waiter.topic == topic && waiter.value == value {
+			close(waiter.done)
+			continue
+		}
+		waiters = append(waiters, waiter)
+	}
+	d.waiters = waiters
+}
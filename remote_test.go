@@ -0,0 +1,96 @@
+package homie
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemotePropertySet(t *testing.T) {
+	source := NewDevice("deviceID", "deviceName")
+	source.AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeBoolean).Settable(true)
+	transport := newMockTransport()
+	discovery := NewDiscovery()
+	require.NoError(t, discovery.Subscribe(transport))
+	discovery.Ingest(source.GetHomieAttributes()...)
+	err := discovery.RemoteProperty("deviceID", "node1", "prop1").Set(false)
+	assert.NoError(t, err)
+	assert.Equal(t, []mockMessage{
+		{"homie/deviceID/node1/prop1/set", QoS, false, "false"},
+	}, transport.messages)
+}
+
+func TestRemotePropertySetErrors(t *testing.T) {
+	source := NewDevice("deviceID", "deviceName")
+	source.AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeBoolean).Settable(true).Node().
+		AddProperty("prop2", "prop2 name", TypeEnum).SetEnumValues("low", "high")
+	transport := newMockTransport()
+	discovery := NewDiscovery()
+	require.NoError(t, discovery.Subscribe(transport))
+	discovery.Ingest(source.GetHomieAttributes()...)
+
+	err := discovery.RemoteProperty("deviceID", "node1", "unknown").Set(false)
+	assert.True(t, errors.Is(err, ErrUnknownProperty))
+
+	err = discovery.RemoteProperty("deviceID", "node1", "prop2").Set("low")
+	assert.True(t, errors.Is(err, ErrNotSettable))
+
+	err = discovery.RemoteProperty("deviceID", "node1", "prop1").Set("yes")
+	assert.True(t, errors.Is(err, ErrInvalidValue))
+
+	assert.Empty(t, transport.messages)
+}
+
+func TestRemotePropertySetWithoutTransport(t *testing.T) {
+	source := NewDevice("deviceID", "deviceName")
+	source.AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeBoolean).Settable(true)
+	discovery := NewDiscovery()
+	discovery.Ingest(source.GetHomieAttributes()...)
+
+	err := discovery.RemoteProperty("deviceID", "node1", "prop1").Set(false)
+	assert.True(t, errors.Is(err, ErrNoTransport))
+}
+
+func TestRemotePropertySetAndWait(t *testing.T) {
+	source := NewDevice("deviceID", "deviceName")
+	source.AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeBoolean).Settable(true)
+	transport := newMockTransport()
+	discovery := NewDiscovery()
+	require.NoError(t, discovery.Subscribe(transport))
+	discovery.Ingest(source.GetHomieAttributes()...)
+	// the device is sending the new value back
+	transport.onPublish = func(topic, payload string) {
+		discovery.HandleMessage("homie/deviceID/node1/prop1", payload)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := discovery.RemoteProperty("deviceID", "node1", "prop1").SetAndWait(ctx, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "false", discovery.Device("deviceID").Node("node1").Property("prop1").GetValue().Value)
+	assert.Empty(t, discovery.waiters)
+}
+
+func TestRemotePropertySetAndWaitTimeout(t *testing.T) {
+	source := NewDevice("deviceID", "deviceName")
+	source.AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeBoolean).Settable(true)
+	transport := newMockTransport()
+	discovery := NewDiscovery()
+	require.NoError(t, discovery.Subscribe(transport))
+	discovery.Ingest(source.GetHomieAttributes()...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := discovery.RemoteProperty("deviceID", "node1", "prop1").SetAndWait(ctx, false)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Empty(t, discovery.waiters)
+}
@@ -0,0 +1,154 @@
+package homie
+
+import (
+	"fmt"
+	"path"
+	"sort"
+)
+
+// RemoveNode removes the node from the device, and returns the topics which must be cleared on the broker
+// (the node attributes and the property values).
+//
+// When a transport is attached, an empty retained payload is published to each of these topics.
+// If the device was already published, it goes back to the "init" state and its description is published again.
+// The values of the node waiting for the end of the rate limit interval are dropped.
+func (d *Device) RemoveNode(id string) ([]string, error) {
+	if d.Node(id) == nil {
+		return nil, fmt.Errorf("%w: '%s'", ErrUnknownNode, id)
+	}
+	return d.removeStructure(func() {
+		d.mutex.Lock()
+		node := d.nodes[id]
+		delete(d.nodes, id)
+		d.mutex.Unlock()
+
+		if node != nil {
+			node.cancelPending()
+		}
+	})
+}
+
+// RemoveProperty removes the property from the node, and returns the topics which must be cleared on the broker
+// (the property attributes and its value).
+//
+// When a transport is attached, an empty retained payload is published to each of these topics.
+// If the device was already published, it goes back to the "init" state and its description is published again.
+//
+// On an element of a node array, the property is removed from the whole array.
+func (n *Node) RemoveProperty(id string) ([]string, error) {
+	if n.parent != nil {
+		return n.parent.RemoveProperty(id)
+	}
+	if n.Property(id) == nil {
+		return nil, fmt.Errorf("%w: '%s'", ErrUnknownProperty, path.Join(n.id, id))
+	}
+	remove := func() {
+		n.mutex.Lock()
+		defer n.mutex.Unlock()
+
+		if prop := n.properties[id]; prop != nil {
+			prop.cancelPending()
+		}
+		delete(n.properties, id)
+		for _, instance := range n.instances {
+			instance.mutex.Lock()
+			if prop := instance.properties[id]; prop != nil {
+				prop.cancelPending()
+			}
+			delete(instance.properties, id)
+			instance.mutex.Unlock()
+		}
+	}
+	if n.device == nil {
+		remove()
+		return nil, nil
+	}
+	return n.device.removeStructure(remove)
+}
+
+// Remove returns all the topics of the device which must be cleared on the broker to remove the device.
+// The $homie attribute comes last, so a controller sees the device removed once everything else is cleared.
+//
+// When a transport is attached, an empty retained payload is published to each of these topics.
+// The definition of the device is kept: it can be published again with Publish.
+func (d *Device) Remove() ([]string, error) {
+	d.mutex.Lock()
+	versionTopic := path.Join(d.prefix, attributeHomieVersion)
+	version := d.majorVersion()
+	d.published = false
+	d.mutex.Unlock()
+
+	// the values waiting for the end of the rate limit interval must not be published after the removal
+	for _, prop := range d.expandedProperties() {
+		prop.cancelPending()
+	}
+
+	topics := make([]string, 0)
+	for topic := range d.retainedTopics() {
+		if topic != versionTopic {
+			topics = append(topics, topic)
+		}
+	}
+	sort.Strings(topics)
+	if version != "5" {
+		topics = append(topics, versionTopic)
+	}
+	return topics, d.clearTopics(topics)
+}
+
+// removeStructure runs the removal and returns the topics which are not published anymore
+func (d *Device) removeStructure(remove func()) ([]string, error) {
+	before := d.retainedTopics()
+	d.mutex.RLock()
+	published := d.published
+	d.mutex.RUnlock()
+	remove()
+	d.structureChanged()
+	after := d.retainedTopics()
+
+	topics := make([]string, 0)
+	for topic := range before {
+		if !after[topic] {
+			topics = append(topics, topic)
+		}
+	}
+	sort.Strings(topics)
+
+	err := d.clearTopics(topics)
+	if err != nil || !published {
+		return topics, err
+	}
+	return topics, d.Publish()
+}
+
+// retainedTopics returns the topics of all the attributes and the values currently set
+func (d *Device) retainedTopics() map[string]bool {
+	topics := make(map[string]bool)
+	for _, attribute := range d.GetHomieAttributes() {
+		topics[attribute.Topic] = true
+	}
+	for _, value := range d.GetValues() {
+		if value.Value != "" {
+			topics[value.Topic] = true
+		}
+	}
+	return topics
+}
+
+// clearTopics publishes an empty retained payload to each topic, if a transport is attached
+func (d *Device) clearTopics(topics []string) error {
+	d.mutex.RLock()
+	transport := d.transport
+	d.mutex.RUnlock()
+
+	if transport == nil {
+		return nil
+	}
+	for _, topic := range topics {
+		err := transport.Publish(topic, QoS, true, "")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package homie
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoveProperty(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName")
+	device.AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeInteger).SetUnit("W").Set(10).Node().
+		AddProperty("prop2", "prop2 name", TypeString)
+	topics, err := device.Node("node1").RemoveProperty("prop1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"homie/deviceID/node1/prop1",
+		"homie/deviceID/node1/prop1/$datatype",
+		"homie/deviceID/node1/prop1/$name",
+		"homie/deviceID/node1/prop1/$unit",
+	}, topics)
+	assert.Nil(t, device.Node("node1").Property("prop1"))
+	assert.Contains(t, device.GetHomieAttributes(), TopicValuePair{"homie/deviceID/node1/$properties", "prop2"})
+
+	_, err = device.Node("node1").RemoveProperty("prop1")
+	assert.True(t, errors.Is(err, ErrUnknownProperty))
+}
+
+func TestRemoveNode(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName")
+	device.AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeInteger).Set(10)
+	device.AddNode("node2", "node2 name", "test2").
+		AddProperty("prop3", "prop3 name", TypeBoolean).Settable(true)
+	topics, err := device.RemoveNode("node2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"homie/deviceID/node2/$name",
+		"homie/deviceID/node2/$properties",
+		"homie/deviceID/node2/$type",
+		"homie/deviceID/node2/prop3/$datatype",
+		"homie/deviceID/node2/prop3/$name",
+		"homie/deviceID/node2/prop3/$settable",
+	}, topics)
+	assert.Nil(t, device.Node("node2"))
+	assert.Contains(t, device.GetHomieAttributes(), TopicValuePair{"homie/deviceID/$nodes", "node1"})
+
+	_, err = device.RemoveNode("node2")
+	assert.True(t, errors.Is(err, ErrUnknownNode))
+}
+
+func TestRemovePropertyFromNodeArray(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName")
+	array := device.AddNodeArray("relays", "Relays", "relay", 1, 2)
+	array.AddProperty("power", "Power", TypeBoolean)
+	array.AddProperty("current", "Current", TypeFloat)
+	array.Index(1).Property("power").Set(true)
+
+	topics, err := array.Index(2).RemoveProperty("power")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"homie/deviceID/relays-1/power",
+		"homie/deviceID/relays-1/power/$datatype",
+		"homie/deviceID/relays-1/power/$name",
+		"homie/deviceID/relays-2/power/$datatype",
+		"homie/deviceID/relays-2/power/$name",
+	}, topics)
+	assert.Nil(t, array.Property("power"))
+	assert.Nil(t, array.Index(1).Property("power"))
+}
+
+func TestRemoveNodeWithTransport(t *testing.T) {
+	transport := newMockTransport()
+	device := NewDevice("deviceID", "deviceName").SetTransport(transport)
+	device.AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeInteger).Set(10)
+	device.AddNode("node2", "node2 name", "test2").
+		AddProperty("prop3", "prop3 name", TypeBoolean).Settable(true)
+	require.NoError(t, device.Publish())
+	transport.messages = transport.messages[:0]
+
+	topics, err := device.RemoveNode("node2")
+	require.NoError(t, err)
+
+	pairs := transport.pairs()
+	require.True(t, len(pairs) > len(topics)+2)
+	// back to init, clear the node, then publish the description again
+	assert.Equal(t, TopicValuePair{"homie/deviceID/$state", "init"}, pairs[0])
+	for i, topic := range topics {
+		assert.Equal(t, TopicValuePair{topic, ""}, pairs[i+1])
+	}
+	assert.Equal(t, TopicValuePair{"homie/deviceID/$state", "init"}, pairs[len(topics)+1])
+	assert.Contains(t, pairs, TopicValuePair{"homie/deviceID/$nodes", "node1"})
+	assert.Equal(t, TopicValuePair{"homie/deviceID/$state", "ready"}, pairs[len(pairs)-1])
+}
+
+func TestRemoveDevice(t *testing.T) {
+	transport := newMockTransport()
+	device := NewDevice("deviceID", "deviceName").SetTransport(transport)
+	device.AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeInteger).Set(10)
+	device.AddNode("node2", "node2 name", "test2").
+		AddProperty("prop3", "prop3 name", TypeBoolean).Settable(true)
+	require.NoError(t, device.Publish())
+	published := make(map[string]bool)
+	for _, message := range transport.messages {
+		published[message.topic] = true
+	}
+	transport.messages = transport.messages[:0]
+
+	topics, err := device.Remove()
+	require.NoError(t, err)
+	assert.Len(t, topics, len(published))
+	assert.Equal(t, "homie/deviceID/$homie", topics[len(topics)-1])
+	for i, message := range transport.messages {
+		assert.True(t, published[message.topic])
+		assert.Equal(t, topics[i], message.topic)
+		assert.Equal(t, "", message.payload)
+		assert.True(t, message.retained)
+	}
+}
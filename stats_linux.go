@@ -0,0 +1,79 @@
+//go:build linux
+// +build linux
+
+package homie
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// AddDefaultProviders registers the stat providers available on this platform.
+// On Linux: cpuload and freeheap
+func (e *LegacyStats) AddDefaultProviders() *LegacyStats {
+	return e.
+		AddProvider(StatCPULoad, ProcLoad).
+		AddProvider(StatFreeHeap, ProcFreeMemory)
+}
+
+// ProcUptime returns the number of seconds since the system boot, from /proc/uptime.
+// Register it as the StatUptime provider to publish the uptime of the system instead of the uptime of the device.
+func ProcUptime() (string, error) {
+	content, err := ioutil.ReadFile("/proc/uptime")
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("unexpected content in /proc/uptime: '%s'", content)
+	}
+	uptime, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(int64(uptime), 10), nil
+}
+
+// ProcLoad returns the CPU load in % of all CPUs, from the 1 minute load average of /proc/loadavg
+func ProcLoad() (string, error) {
+	content, err := ioutil.ReadFile("/proc/loadavg")
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("unexpected content in /proc/loadavg: '%s'", content)
+	}
+	load, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(int64(load*100/float64(runtime.NumCPU())), 10), nil
+}
+
+// ProcFreeMemory returns the available memory in bytes, from /proc/meminfo
+func ProcFreeMemory() (string, error) {
+	content, err := ioutil.ReadFile("/proc/meminfo")
+	if err != nil {
+		return "", err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemAvailable:" {
+			continue
+		}
+		available, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return "", err
+		}
+		// the value is in kB
+		return strconv.FormatInt(available*1024, 10), nil
+	}
+	return "", fmt.Errorf("MemAvailable not found in /proc/meminfo")
+}
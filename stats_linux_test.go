@@ -0,0 +1,31 @@
+//go:build linux
+// +build linux
+
+package homie
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcProviders(t *testing.T) {
+	for name, provider := range map[string]StatProvider{
+		"uptime":   ProcUptime,
+		"load":     ProcLoad,
+		"freeheap": ProcFreeMemory,
+	} {
+		t.Run(name, func(t *testing.T) {
+			value, err := provider()
+			assert.NoError(t, err)
+			_, err = strconv.ParseInt(value, 10, 64)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestDefaultProviders(t *testing.T) {
+	stats := NewLegacyStats().AddDefaultProviders()
+	assert.Len(t, stats.providers, 2)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package homie
+
+// AddDefaultProviders registers the stat providers available on this platform.
+// There's no default provider on this platform.
+func (e *LegacyStats) AddDefaultProviders() *LegacyStats {
+	return e
+}
@@ -0,0 +1,36 @@
+package homie
+
+// QoS is the MQTT quality of service used by the Homie convention for all messages
+//
+// see documentation: https://homieiot.github.io/specification/#qos-and-retained-messages
+const QoS byte = 1
+
+// MessageHandler is the signature of the callback receiving a message from a MQTT subscription
+type MessageHandler func(topic, payload string)
+
+// Transport is the interface to the MQTT client of your choice.
+//
+// Once a transport is attached to a device, the library can publish the device description,
+// the property values and the device state, and subscribe to the property setters for you.
+type Transport interface {
+	// Publish sends the payload to the topic
+	Publish(topic string, qos byte, retained bool, payload string) error
+	// Subscribe registers a handler receiving the messages sent to the topic
+	Subscribe(topic string, qos byte, handler MessageHandler) error
+	// Close disconnects the client from the MQTT broker
+	Close() error
+}
+
+// Will is the MQTT last will and testament the client must configure before connecting to the broker
+type Will struct {
+	Topic    string
+	Payload  string
+	QoS      byte
+	Retained bool
+}
+
+// WillSetter is implemented by the transports able to configure the last will of the MQTT client.
+// The will of the device is set automatically when such a transport is attached to the device.
+type WillSetter interface {
+	SetWill(will Will)
+}
@@ -0,0 +1,189 @@
+package homie
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockMessage struct {
+	topic    string
+	qos      byte
+	retained bool
+	payload  string
+}
+
+type mockTransport struct {
+	mutex         sync.Mutex
+	messages      []mockMessage
+	subscriptions map[string]MessageHandler
+	closed        bool
+	// onPublish simulates a reaction to a published message
+	onPublish func(topic, payload string)
+}
+
+func newMockTransport() *mockTransport {
+	return &mockTransport{
+		messages:      make([]mockMessage, 0),
+		subscriptions: make(map[string]MessageHandler),
+	}
+}
+
+func (t *mockTransport) Publish(topic string, qos byte, retained bool, payload string) error {
+	t.mutex.Lock()
+	t.messages = append(t.messages, mockMessage{topic, qos, retained, payload})
+	t.mutex.Unlock()
+	if t.onPublish != nil {
+		t.onPublish(topic, payload)
+	}
+	return nil
+}
+
+func (t *mockTransport) Subscribe(topic string, qos byte, handler MessageHandler) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.subscriptions[topic] = handler
+	return nil
+}
+
+func (t *mockTransport) Close() error {
+	t.closed = true
+	return nil
+}
+
+// send simulates a message coming from the broker
+func (t *mockTransport) send(topic, payload string) {
+	t.mutex.Lock()
+	handler, ok := t.subscriptions[topic]
+	t.mutex.Unlock()
+	if ok {
+		handler(topic, payload)
+	}
+}
+
+func (t *mockTransport) pairs() []TopicValuePair {
+	pairs := make([]TopicValuePair, len(t.messages))
+	for i, message := range t.messages {
+		pairs[i] = TopicValuePair{message.topic, message.payload}
+	}
+	return pairs
+}
+
+func TestPublishWithoutTransport(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName")
+	assert.Equal(t, ErrNoTransport, device.Publish())
+}
+
+func TestPublishDevice(t *testing.T) {
+	transport := newMockTransport()
+	device := NewDevice("deviceID", "deviceName").SetTransport(transport)
+	device.
+		AddNode("node1", "node1 name", "test1").
+		AddProperty("prop1", "prop1 name", TypeBoolean).Settable(true).Node().
+		AddProperty("prop2", "prop2 name", TypeInteger).SetRetained(false)
+
+	err := device.Publish()
+	require.NoError(t, err)
+
+	pairs := transport.pairs()
+	require.NotEmpty(t, pairs)
+	assert.Equal(t, TopicValuePair{"homie/deviceID/$state", "init"}, pairs[0])
+	assert.Equal(t, TopicValuePair{"homie/deviceID/$state", "ready"}, pairs[len(pairs)-1])
+	assert.ElementsMatch(t, pairs[1:len(pairs)-1], []TopicValuePair{
+		{"homie/deviceID/$homie", "4.0.0"},
+		{"homie/deviceID/$name", "deviceName"},
+		{"homie/deviceID/$nodes", "node1"},
+		{"homie/deviceID/$extensions", ""},
+		{"homie/deviceID/node1/$name", "node1 name"},
+		{"homie/deviceID/node1/$type", "test1"},
+		{"homie/deviceID/node1/$properties", "prop1,prop2"},
+		{"homie/deviceID/node1/prop1/$name", "prop1 name"},
+		{"homie/deviceID/node1/prop1/$datatype", "boolean"},
+		{"homie/deviceID/node1/prop1/$settable", "true"},
+		{"homie/deviceID/node1/prop2/$name", "prop2 name"},
+		{"homie/deviceID/node1/prop2/$datatype", "integer"},
+		{"homie/deviceID/node1/prop2/$retained", "false"},
+	})
+	for _, message := range transport.messages {
+		assert.Equal(t, QoS, message.qos)
+		assert.True(t, message.retained)
+	}
+	assert.Len(t, transport.subscriptions, 1)
+	assert.NotNil(t, transport.subscriptions["homie/deviceID/node1/prop1/set"])
+}
+
+func TestPublishValuesAndState(t *testing.T) {
+	transport := newMockTransport()
+	device := NewDevice("deviceID", "deviceName").SetTransport(transport)
+	node := device.AddNode("node1", "node1 name", "test1")
+	node.AddProperty("prop1", "prop1 name", TypeInteger).Set(10)
+	node.AddProperty("prop2", "prop2 name", TypeInteger).SetRetained(false).Set(20)
+
+	transport.messages = transport.messages[:0]
+	device.SetState(StateSleeping)
+
+	assert.Equal(t, []mockMessage{
+		{"homie/deviceID/$state", QoS, true, "sleeping"},
+	}, transport.messages)
+
+	transport.messages = transport.messages[:0]
+	node.Property("prop2").Set(21)
+
+	assert.Equal(t, []mockMessage{
+		{"homie/deviceID/node1/prop2", QoS, false, "21"},
+	}, transport.messages)
+}
+
+func TestIncomingSetCommand(t *testing.T) {
+	transport := newMockTransport()
+	device := NewDevice("deviceID", "deviceName").SetTransport(transport)
+	property := device.AddNode("node1", "node1 name", "test1").AddProperty("prop1", "prop1 name", TypeString).Settable(true)
+
+	err := device.Publish()
+	require.NoError(t, err)
+	transport.messages = transport.messages[:0]
+
+	transport.send("homie/deviceID/node1/prop1/set", "new value")
+	assert.Equal(t, "new value", property.GetValue().Value)
+	assert.Equal(t, []mockMessage{
+		{"homie/deviceID/node1/prop1", QoS, true, "new value"},
+	}, transport.messages)
+}
+
+type mockWillTransport struct {
+	*mockTransport
+	will Will
+}
+
+func (t *mockWillTransport) SetWill(will Will) {
+	t.will = will
+}
+
+func TestLastWill(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName")
+	assert.Equal(t, Will{"homie/deviceID/$state", "lost", QoS, true}, device.LastWill())
+
+	device.SetVersion(Version5)
+	assert.Equal(t, Will{"homie/5/deviceID/$state", "lost", QoS, true}, device.LastWill())
+}
+
+func TestLastWillAppliedOnTransport(t *testing.T) {
+	transport := &mockWillTransport{mockTransport: newMockTransport()}
+	NewDevice("deviceID", "deviceName").SetTransport(transport)
+	assert.Equal(t, Will{"homie/deviceID/$state", "lost", QoS, true}, transport.will)
+}
+
+func TestDisconnect(t *testing.T) {
+	transport := newMockTransport()
+	device := NewDevice("deviceID", "deviceName")
+	assert.Equal(t, ErrNoTransport, device.Disconnect())
+
+	device.SetTransport(transport)
+	assert.NoError(t, device.Disconnect())
+	assert.Equal(t, []mockMessage{{"homie/deviceID/$state", QoS, true, "disconnected"}}, transport.messages)
+	assert.True(t, transport.closed)
+	assert.Equal(t, TopicValuePair{"homie/deviceID/$state", "disconnected"}, device.GetState())
+}
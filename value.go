@@ -0,0 +1,159 @@
+package homie
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// formatValue converts a value into its canonical payload for the data type, then validates it against the format.
+//
+// see documentation: https://homieiot.github.io/specification/#payload
+func formatValue(dataType PropertyType, format string, value interface{}) (string, error) {
+	payload, err := canonicalValue(dataType, value)
+	if err != nil {
+		return "", err
+	}
+	err = validateValue(dataType, format, payload)
+	if err != nil {
+		return "", err
+	}
+	return payload, nil
+}
+
+// canonicalValue converts a value into a payload for the data type
+func canonicalValue(dataType PropertyType, value interface{}) (string, error) {
+	if value == nil {
+		if dataType == TypeString {
+			return "", nil
+		}
+		return "", fmt.Errorf("%w: nil value for a %s property", ErrInvalidValue, dataType)
+	}
+	switch dataType {
+	case TypeDatetime:
+		switch typed := value.(type) {
+		case time.Time:
+			return FormatDatetime(typed), nil
+		case string:
+			datetime, err := ParseDatetime(typed)
+			if err != nil {
+				return "", err
+			}
+			return FormatDatetime(datetime), nil
+		}
+		return "", fmt.Errorf("%w: %#v is not a datetime", ErrInvalidValue, value)
+
+	case TypeDuration:
+		switch typed := value.(type) {
+		case time.Duration:
+			return FormatDuration(typed), nil
+		case string:
+			duration, err := ParseDuration(typed)
+			if err != nil {
+				return "", err
+			}
+			return FormatDuration(duration), nil
+		}
+		return "", fmt.Errorf("%w: %#v is not a duration", ErrInvalidValue, value)
+	}
+
+	reflected := reflect.ValueOf(value)
+	kind := reflected.Kind()
+
+	switch dataType {
+	case TypeInteger:
+		switch {
+		case isInt(kind):
+			return strconv.FormatInt(reflected.Int(), 10), nil
+		case isUint(kind):
+			if reflected.Uint() > math.MaxInt64 {
+				break
+			}
+			return strconv.FormatUint(reflected.Uint(), 10), nil
+		case isFloat(kind):
+			number := reflected.Float()
+			if number != math.Trunc(number) || number < math.MinInt64 || number >= math.MaxInt64 {
+				break
+			}
+			return strconv.FormatInt(int64(number), 10), nil
+		case kind == reflect.String:
+			number, err := strconv.ParseInt(reflected.String(), 10, 64)
+			if err != nil {
+				break
+			}
+			return strconv.FormatInt(number, 10), nil
+		}
+		return "", fmt.Errorf("%w: %#v is not an integer", ErrInvalidValue, value)
+
+	case TypeFloat:
+		switch {
+		case isInt(kind):
+			return strconv.FormatInt(reflected.Int(), 10), nil
+		case isUint(kind):
+			return strconv.FormatUint(reflected.Uint(), 10), nil
+		case isFloat(kind):
+			return formatFloat(reflected.Float(), reflected.Type().Bits())
+		case kind == reflect.String:
+			number, err := strconv.ParseFloat(reflected.String(), 64)
+			if err != nil {
+				break
+			}
+			return formatFloat(number, 64)
+		}
+		return "", fmt.Errorf("%w: %#v is not a float", ErrInvalidValue, value)
+
+	case TypeBoolean:
+		switch kind {
+		case reflect.Bool:
+			return strconv.FormatBool(reflected.Bool()), nil
+		case reflect.String:
+			if reflected.String() == "true" || reflected.String() == "false" {
+				return reflected.String(), nil
+			}
+		}
+		return "", fmt.Errorf("%w: %#v is not a boolean", ErrInvalidValue, value)
+
+	case TypeEnum, TypeColor:
+		if stringer, ok := value.(fmt.Stringer); ok {
+			return stringer.String(), nil
+		}
+		if kind == reflect.String {
+			return reflected.String(), nil
+		}
+		return "", fmt.Errorf("%w: %#v is not a string", ErrInvalidValue, value)
+	}
+	return fmt.Sprintf("%v", value), nil
+}
+
+// formatFloat never uses the exponent notation
+func formatFloat(number float64, bitSize int) (string, error) {
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return "", fmt.Errorf("%w: %v is not a valid float", ErrInvalidValue, number)
+	}
+	return strconv.FormatFloat(number, 'f', -1, bitSize), nil
+}
+
+func isInt(kind reflect.Kind) bool {
+	return kind == reflect.Int || kind == reflect.Int8 || kind == reflect.Int16 || kind == reflect.Int32 || kind == reflect.Int64
+}
+
+func isUint(kind reflect.Kind) bool {
+	return kind == reflect.Uint || kind == reflect.Uint8 || kind == reflect.Uint16 || kind == reflect.Uint32 || kind == reflect.Uint64
+}
+
+func isFloat(kind reflect.Kind) bool {
+	return kind == reflect.Float32 || kind == reflect.Float64
+}
+
+// validateValue checks the payload is valid for the data type and format of a property.
+// No value is valid with a malformed format.
+func validateValue(dataType PropertyType, format, value string) error {
+	parsed, err := ParseFormat(dataType, format)
+	if err != nil {
+		// no value can be checked against a malformed format
+		return fmt.Errorf("%w: %v", ErrInvalidValue, err)
+	}
+	return parsed.Validate(value)
+}
@@ -0,0 +1,125 @@
+package homie
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateValue(t *testing.T) {
+	testData := []struct {
+		dataType PropertyType
+		format   string
+		value    string
+		valid    bool
+	}{
+		{TypeString, "", "", true},
+		{TypeString, "", "anything", true},
+		{TypeInteger, "", "10", true},
+		{TypeInteger, "", "-10", true},
+		{TypeInteger, "", "1.5", false},
+		{TypeInteger, "", "", false},
+		{TypeInteger, "0:10", "10", true},
+		{TypeInteger, "0:10", "11", false},
+		{TypeInteger, "0:", "-1", false},
+		{TypeInteger, ":0", "1", false},
+		{TypeFloat, "", "1.5", true},
+		{TypeFloat, "", "10", true},
+		{TypeFloat, "", "NaN", false},
+		{TypeFloat, "", "+Inf", false},
+		{TypeFloat, "-1.5:1.5", "-1.6", false},
+		{TypeInteger, "0.5:10", "100", false},
+		{TypeInteger, "0:10:2", "4", false},
+		{TypeFloat, "high", "1.5", false},
+		{TypeBoolean, "", "true", true},
+		{TypeBoolean, "", "false", true},
+		{TypeBoolean, "", "yes", false},
+		{TypeBoolean, "", "True", false},
+		{TypeEnum, "low,medium,high", "medium", true},
+		{TypeEnum, "low,medium,high", "none", false},
+		{TypeColor, "rgb", "255,0,128", true},
+		{TypeColor, "rgb", "256,0,128", false},
+		{TypeColor, "rgb", "255,0", false},
+		{TypeColor, "hsv", "360,100,100", true},
+		{TypeColor, "hsv", "360,101,100", false},
+		{TypeColor, "", "0,0,0", false},
+	}
+	for _, testItem := range testData {
+		t.Run(string(testItem.dataType)+" "+testItem.format+" "+testItem.value, func(t *testing.T) {
+			err := validateValue(testItem.dataType, testItem.format, testItem.value)
+			if testItem.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, ErrInvalidValue))
+			}
+		})
+	}
+}
+
+type testEnum string
+
+func (e testEnum) String() string {
+	return string(e)
+}
+
+func TestFormatValue(t *testing.T) {
+	testData := []struct {
+		dataType PropertyType
+		format   string
+		value    interface{}
+		payload  string
+		valid    bool
+	}{
+		{TypeString, "", nil, "", true},
+		{TypeString, "", "value", "value", true},
+		{TypeString, "", 10, "10", true},
+		{TypeInteger, "", nil, "", false},
+		{TypeInteger, "", 10, "10", true},
+		{TypeInteger, "", int8(-10), "-10", true},
+		{TypeInteger, "", uint16(10), "10", true},
+		{TypeInteger, "", uint64(math.MaxUint64), "", false},
+		{TypeInteger, "", 10.0, "10", true},
+		{TypeInteger, "", 10.5, "", false},
+		{TypeInteger, "", 1e20, "", false},
+		{TypeInteger, "", "007", "7", true},
+		{TypeInteger, "", "seven", "", false},
+		{TypeInteger, "", true, "", false},
+		{TypeInteger, "0:10", 11, "", false},
+		{TypeFloat, "", 10, "10", true},
+		{TypeFloat, "", 28.5, "28.5", true},
+		{TypeFloat, "", float32(28.1), "28.1", true},
+		{TypeFloat, "", 1e6, "1000000", true},
+		{TypeFloat, "", 1e-7, "0.0000001", true},
+		{TypeFloat, "", math.NaN(), "", false},
+		{TypeFloat, "", math.Inf(1), "", false},
+		{TypeFloat, "", "1e3", "1000", true},
+		{TypeFloat, "", "NaN", "", false},
+		{TypeFloat, "", nil, "", false},
+		{TypeBoolean, "", true, "true", true},
+		{TypeBoolean, "", false, "false", true},
+		{TypeBoolean, "", "true", "true", true},
+		{TypeBoolean, "", "yes", "", false},
+		{TypeBoolean, "", 1, "", false},
+		{TypeEnum, "low,high", "low", "low", true},
+		{TypeEnum, "low,high", testEnum("high"), "high", true},
+		{TypeEnum, "low,high", "medium", "", false},
+		{TypeEnum, "low,high", 1, "", false},
+		{TypeColor, "rgb", "10,20,30", "10,20,30", true},
+		{TypeColor, "rgb", "10,20", "", false},
+		{TypeColor, "hsv", "300,50,50", "300,50,50", true},
+	}
+	for _, testItem := range testData {
+		t.Run(fmt.Sprintf("%s %s %v", testItem.dataType, testItem.format, testItem.value), func(t *testing.T) {
+			payload, err := formatValue(testItem.dataType, testItem.format, testItem.value)
+			if testItem.valid {
+				assert.NoError(t, err)
+				assert.Equal(t, testItem.payload, payload)
+			} else {
+				assert.True(t, errors.Is(err, ErrInvalidValue))
+			}
+		})
+	}
+}
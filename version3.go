@@ -0,0 +1,108 @@
+package homie
+
+import (
+	"path"
+	"strings"
+	"time"
+)
+
+// SetLocalIP defines the IP of the device on the local network (Homie 3 $localip attribute, also published by the LegacyFirmware extension)
+func (d *Device) SetLocalIP(ip string) *Device {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.localIP = ip
+	return d
+}
+
+// SetMAC defines the MAC address of the network interface (Homie 3 $mac attribute).
+// The address is published in uppercase using ":" as a separator.
+func (d *Device) SetMAC(mac string) *Device {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.mac = strings.ToUpper(strings.ReplaceAll(mac, "-", ":"))
+	return d
+}
+
+// SetFirmware defines the name and the version of the firmware running on the device (Homie 3 $fw attributes, also published by the LegacyFirmware extension)
+func (d *Device) SetFirmware(name, version string) *Device {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.fwName = name
+	d.fwVersion = version
+	return d
+}
+
+// SetImplementation defines an identifier of the Homie implementation (Homie 3 $implementation attribute).
+// The default is "go-homie".
+func (d *Device) SetImplementation(implementation string) *Device {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.implementation = implementation
+	return d
+}
+
+// SetStatsInterval defines the interval at which the stats are refreshed (Homie 3 $stats/interval attribute, also published by the LegacyStats extension).
+// The default is 60 seconds.
+func (d *Device) SetStatsInterval(interval time.Duration) *Device {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.statsInterval = interval
+	return d
+}
+
+// Uptime returns the time elapsed since the device was created
+func (d *Device) Uptime() time.Duration {
+	return time.Since(d.started)
+}
+
+// getVersion3Attributes returns the device attributes only defined in Homie 3 (the mutex must not be held by the caller):
+// they are the same as the legacy firmware and legacy stats extensions of Homie 4.
+// The stats are read from the LegacyStats extension added to the device, if any.
+//
+// $localip, $mac and $fw are required by the specification, but they are not published until they are defined:
+// an empty retained payload would delete the topic.
+//
+// see documentation: https://homieiot.github.io/specification/spec-core-v3_0_1/#device-attributes
+func (d *Device) getVersion3Attributes() []TopicValuePair {
+	relative := NewLegacyFirmware().Attributes(d)
+	d.mutex.RLock()
+	prefix := d.prefix
+	implementation := d.implementation
+	stats := d.legacyStats()
+	d.mutex.RUnlock()
+
+	statsAttributes := stats.Attributes(d)
+	names := make([]string, 0, len(statsAttributes))
+	for _, attribute := range statsAttributes {
+		if name := path.Base(attribute.Topic); name != "interval" {
+			names = append(names, name)
+		}
+	}
+	relative = append(relative,
+		TopicValuePair{attributeImplementation, implementation},
+		TopicValuePair{attributeStats, strings.Join(names, ",")},
+	)
+	relative = append(relative, statsAttributes...)
+
+	attributes := make([]TopicValuePair, len(relative))
+	for i, attribute := range relative {
+		attributes[i] = TopicValuePair{path.Join(prefix, attribute.Topic), attribute.Value}
+	}
+	return attributes
+}
+
+// legacyStats returns the LegacyStats extension added to the device, or a new one only publishing the uptime.
+// The mutex must be held by the caller.
+func (d *Device) legacyStats() *LegacyStats {
+	for _, extension := range d.extensions {
+		if stats, ok := extension.(*LegacyStats); ok {
+			return stats
+		}
+	}
+	return NewLegacyStats()
+}
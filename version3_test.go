@@ -0,0 +1,64 @@
+package homie
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersion3Attributes(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName").
+		SetVersion(Version3).
+		SetLocalIP("192.168.0.10").
+		SetMAC("de-ad-be-ef-fe-ed").
+		SetFirmware("firmware", "1.0.0").
+		SetImplementation("unit-test").
+		SetStatsInterval(30 * time.Second)
+	device.started = time.Now().Add(-90 * time.Second)
+	device.AddNode("node1", "node1 name", "test1").AddProperty("prop1", "prop1 name", TypeBoolean)
+
+	assert.ElementsMatch(t, device.GetHomieAttributes(), []TopicValuePair{
+		{"homie/deviceID/$homie", "3.0.1"},
+		{"homie/deviceID/$name", "deviceName"},
+		{"homie/deviceID/$state", "init"},
+		{"homie/deviceID/$localip", "192.168.0.10"},
+		{"homie/deviceID/$mac", "DE:AD:BE:EF:FE:ED"},
+		{"homie/deviceID/$fw/name", "firmware"},
+		{"homie/deviceID/$fw/version", "1.0.0"},
+		{"homie/deviceID/$implementation", "unit-test"},
+		{"homie/deviceID/$stats", "uptime"},
+		{"homie/deviceID/$stats/interval", "30"},
+		{"homie/deviceID/$stats/uptime", "90"},
+		{"homie/deviceID/$nodes", "node1"},
+		{"homie/deviceID/node1/$name", "node1 name"},
+		{"homie/deviceID/node1/$type", "test1"},
+		{"homie/deviceID/node1/$properties", "prop1"},
+		{"homie/deviceID/node1/prop1/$name", "prop1 name"},
+		{"homie/deviceID/node1/prop1/$datatype", "boolean"},
+	})
+}
+
+func TestVersion3Defaults(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName").SetVersion(Version3)
+	attributes := device.GetHomieAttributes()
+	assert.Contains(t, attributes, TopicValuePair{"homie/deviceID/$implementation", "go-homie"})
+	assert.Contains(t, attributes, TopicValuePair{"homie/deviceID/$stats/interval", "60"})
+	assert.NotContains(t, attributes, TopicValuePair{"homie/deviceID/$extensions", ""})
+	// undefined attributes are not published
+	for _, attribute := range []string{"$localip", "$mac", "$fw/name", "$fw/version"} {
+		assert.NotContains(t, attributes, TopicValuePair{"homie/deviceID/" + attribute, ""})
+	}
+}
+
+func TestVersion3StatsProviders(t *testing.T) {
+	device := NewDevice("deviceID", "deviceName").SetVersion(Version3)
+	device.AddExtension(NewLegacyStats().
+		AddProvider(StatSignal, func() (string, error) { return "80", nil }).
+		AddProvider(StatCPUTemp, func() (string, error) { return "45", nil }))
+
+	attributes := device.GetHomieAttributes()
+	assert.Contains(t, attributes, TopicValuePair{"homie/deviceID/$stats", "uptime,cputemp,signal"})
+	assert.Contains(t, attributes, TopicValuePair{"homie/deviceID/$stats/signal", "80"})
+	assert.Contains(t, attributes, TopicValuePair{"homie/deviceID/$stats/cputemp", "45"})
+}